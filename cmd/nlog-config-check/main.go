@@ -0,0 +1,61 @@
+// Command nlog-config-check checks nlog config files, printing errors with
+// their config paths. It exits with status 1 if a file can not be loaded or
+// is not valid.
+//
+// Usage:
+//
+//	nlog-config-check [-base log] [-env-prefix NLOG_] file...
+//
+// Format of files is detected by extension, .json for json, .toml for toml
+// and yaml for others.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/derkan/nlog/loader"
+)
+
+func main() {
+	base := flag.String("base", "log", "root key of logging config, empty for whole file")
+	envPrefix := flag.String("env-prefix", "NLOG_", "prefix of environment variables overriding config values, empty to disable")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlog-config-check [-base key] [-env-prefix prefix] file...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	status := 0
+	for _, filename := range flag.Args() {
+		if !check(filename, *base, *envPrefix) {
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+// check prints errors of config file and returns true if it is valid
+func check(filename, base, envPrefix string) bool {
+	l, err := loader.FromFile(filename, base, loader.WithEnvPrefix(envPrefix))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+		return false
+	}
+	for _, w := range l.Warnings() {
+		fmt.Fprintf(os.Stderr, "%s: warning: %v\n", filename, w)
+	}
+	errs := l.Validate()
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+	}
+	if errs != nil {
+		return false
+	}
+	fmt.Printf("%s: ok\n", filename)
+	return true
+}
@@ -7,8 +7,8 @@ import (
 	"github.com/derkan/nlog/formatter/console"
 	"github.com/derkan/nlog/formatter/json"
 	"github.com/derkan/nlog/log"
-	"github.com/derkan/nlog/writer"
 	fl "github.com/derkan/nlog/writer/filerotater"
+	sl "github.com/derkan/nlog/writer/syslog"
 )
 
 // jsoniter "github.com/json-iterator/go"
@@ -38,7 +38,7 @@ func main() {
 					), 100),
 			)), log.WithFormatter(
 			console.NewFormatter(
-				console.WithParallelWriter(writer.NewSysLogWriter("MYAPP"), 100, nlog.DEBUG),
+				console.WithParallelWriter(sl.NewSyslogWriter(sl.WithAppName("MYAPP")), 100, nlog.DEBUG),
 			),
 		))
 	log.Infof("test: %d", 123)
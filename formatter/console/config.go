@@ -87,12 +87,33 @@ var LevelColors = map[nlog.Level]string{
 	nlog.DEBUG:   DebugColor,
 }
 
+// ColorNames maps color names used in config files to ANSI color codes
+var ColorNames = map[string]string{
+	"none":         NoColor,
+	"black":        Black,
+	"red":          Red,
+	"green":        Green,
+	"yellow":       Yellow,
+	"blue":         Blue,
+	"magenta":      Magenta,
+	"cyan":         Cyan,
+	"white":        White,
+	"black_bold":   BlackBold,
+	"red_bold":     RedBold,
+	"green_bold":   GreenBold,
+	"yellow_bold":  YellowBold,
+	"blue_bold":    BlueBold,
+	"magenta_bold": MagentaBold,
+	"cyan_bold":    CyanBold,
+	"white_bold":   WhiteBold,
+}
+
 // config is for logger settings
 type config struct {
 	// Level is logging level
 	Level nlog.Level `json:"level" yaml:"level"`
 	// NoPrintLevel if set level string will not be written
-	NoPrintLevel bool `json:"no_print_level" yaml:"print_level"`
+	NoPrintLevel bool `json:"no_print_level" yaml:"no_print_level"`
 	// Date sets whether to print date or not in layout 2006-01-02
 	Date bool `json:"date" yaml:"date"`
 	// Whether to print as string time, resolution can be set using TimeResolution
@@ -117,6 +138,8 @@ type config struct {
 	Hooks []nlog.Hook
 	// Colored determines whether to print in color
 	Colored bool
+	// LevelColors overrides colors of level names, see WithLevelColor
+	LevelColors map[nlog.Level]string
 }
 
 // option is function type used for setting console logging config attributes
@@ -129,6 +152,17 @@ func WithColor() option {
 	}
 }
 
+// WithLevelColor sets color of level name of given level for this formatter,
+// instead of global colors like ErrorColor
+func WithLevelColor(lvl nlog.Level, color string) option {
+	return func(c *config) {
+		if c.LevelColors == nil {
+			c.LevelColors = make(map[nlog.Level]string)
+		}
+		c.LevelColors[lvl] = color
+	}
+}
+
 // WithMarshallFn adds marshalling with specified func
 // default is json.Marshall
 func WithMarshallFn(fn nlog.MarshallFn) option {
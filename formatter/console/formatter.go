@@ -3,7 +3,6 @@ package console
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/derkan/nlog"
@@ -11,7 +10,6 @@ import (
 	"github.com/derkan/nlog/loader"
 	"github.com/derkan/nlog/pool"
 	"github.com/derkan/nlog/writer"
-	fl "github.com/derkan/nlog/writer/filerotater"
 )
 
 // Formatter logs pretty
@@ -39,6 +37,12 @@ func NewFormatter(opts ...option) *Formatter {
 	return c
 }
 
+func init() {
+	nlog.RegisterFormatter("console", func(cfg interface{}, appName string) (nlog.Formatter, error) {
+		return NewFromConfig(cfg.(loader.Formatter), appName), nil
+	})
+}
+
 // NewFromConfig builds a console formatter from given loader config
 // appName is used in syslog
 func NewFromConfig(f loader.Formatter, appName string) *Formatter {
@@ -54,50 +58,27 @@ func NewFromConfig(f loader.Formatter, appName string) *Formatter {
 		TimeResolution:     f.TimeResolution,
 		TimeUTC:            f.TimeUTC,
 		UnixTime:           f.UnixTime,
+		Hooks:              formatter.NewHooks(f.Hooks),
+		MarshallFn:         formatter.NewMarshallFn(f.MarshallFn),
 	}}
-
-	var parallelW []*writer.ParallelWriter
-	var normalW []*writer.Writer
-	var wrt io.WriteCloser
-	for _, w := range f.Writers {
-		switch w.Type {
-		case "stdout":
-			wrt = os.Stdout
-		case "stderr":
-			wrt = os.Stderr
-		case "syslog":
-			wrt = writer.NewSysLogWriter(appName)
-		case "filerotator":
-			wrt = &fl.Rotater{
-				Filename:   w.Filename,
-				Compress:   w.Compress,
-				MaxAge:     w.MaxAge,
-				LocalTime:  !w.UTC,
-				MaxBackups: w.MaxBackups,
-				MaxSize:    w.MaxSize,
-			}
-		default:
-			continue
-		}
-		if f.LeveledType == "parallel" {
-			parallelW = append(parallelW, writer.NewParellelWriter(wrt, w.Level, w.QueueLen))
-		} else {
-			normalW = append(normalW, writer.NewWriter(wrt, w.Level))
-		}
-	}
-	if len(normalW) > 0 {
-		c.cfg.Writer = writer.NewMultiWriter(normalW...)
+	if f.FileLocStrip != "" {
+		WithStripPath(f.FileLocStrip)(c.cfg)
 	}
-	if len(parallelW) > 0 {
-		c.cfg.Writer = writer.NewParallelMultiWriter(parallelW...)
+	for name, color := range f.Colors {
+		lvl, ok := loader.LevelCodes[name]
+		if code, known := ColorNames[color]; ok && known {
+			WithLevelColor(lvl, code)(c.cfg)
+		}
 	}
+
+	c.cfg.Writer = formatter.NewMultiWriter(f, appName)
 	c.SetDefaults()
 	return c
 }
 
 func (cl *Formatter) SetDefaults() {
 	if cl.cfg.Writer == nil {
-		cl.cfg.Writer = writer.NewMultiWriter(writer.NewWriter(os.Stderr, cl.cfg.Level))
+		cl.cfg.Writer = writer.NewMultiWriter(writer.NewWriter(writer.NewStdWriter(os.Stderr), cl.cfg.Level))
 	}
 	if cl.cfg.MarshallFn == nil {
 		cl.cfg.MarshallFn = json.Marshal
@@ -110,8 +91,14 @@ func (cl *Formatter) GetCallDepth(sub int) int {
 	return cl.cfg.FileLocCallerDepth - sub
 }
 
+// Writer returns writers of formatter
+func (cl *Formatter) Writer() writer.LeveledMultiWriter {
+	return cl.cfg.Writer
+}
+
 // Flush flushes to disk and closes writers
 func (cl *Formatter) Flush() {
+	cl.cfg.Writer.Sync()
 	cl.cfg.Writer.Close()
 }
 
@@ -137,11 +124,11 @@ func (cl *Formatter) Init() {
 		initColor(cl.cfg.Writer)
 		cl.strW = strC
 		cl.intW = intC
-		cl.debugStr = fmt.Sprintf("%s%s%s ", DebugColor, nlog.DebugStr, ColorReset)
-		cl.infoStr = fmt.Sprintf("%s%s%s ", InfoColor, nlog.InfoStr, ColorReset)
-		cl.warnStr = fmt.Sprintf("%s%s%s ", WarnColor, nlog.WarnStr, ColorReset)
-		cl.errorStr = fmt.Sprintf("%s%s%s ", ErrorColor, nlog.ErrorStr, ColorReset)
-		cl.fatalStr = fmt.Sprintf("%s%s%s ", FatalColor, nlog.FatalStr, ColorReset)
+		cl.debugStr = fmt.Sprintf("%s%s%s ", cl.levelColor(nlog.DEBUG, DebugColor), nlog.DebugStr, ColorReset)
+		cl.infoStr = fmt.Sprintf("%s%s%s ", cl.levelColor(nlog.INFO, InfoColor), nlog.InfoStr, ColorReset)
+		cl.warnStr = fmt.Sprintf("%s%s%s ", cl.levelColor(nlog.WARNING, WarnColor), nlog.WarnStr, ColorReset)
+		cl.errorStr = fmt.Sprintf("%s%s%s ", cl.levelColor(nlog.ERROR, ErrorColor), nlog.ErrorStr, ColorReset)
+		cl.fatalStr = fmt.Sprintf("%s%s%s ", cl.levelColor(nlog.FATAL, FatalColor), nlog.FatalStr, ColorReset)
 	} else {
 		cl.strW = strW
 		cl.intW = intW
@@ -153,6 +140,14 @@ func (cl *Formatter) Init() {
 	}
 }
 
+// levelColor returns color of level set by WithLevelColor, or def
+func (cl *Formatter) levelColor(lvl nlog.Level, def string) string {
+	if c, ok := cl.cfg.LevelColors[lvl]; ok {
+		return c
+	}
+	return def
+}
+
 // levelStr gets formatted level string
 func (cl *Formatter) levelStr(lvl nlog.Level) string {
 	switch lvl {
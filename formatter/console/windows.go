@@ -8,8 +8,13 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-// InitColor is needed to init windows console for ANSI colors
-func InitColor(w io.Writer) {
+// file is implemented by console files like os.Stdout
+type file interface {
+	Fd() uintptr
+}
+
+// initColor is needed to init windows console for ANSI colors
+func initColor(w io.Writer) {
 	if f, ok := w.(file); ok {
 		setConsoleMode(windows.Handle(f.Fd()), windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
 	}
@@ -0,0 +1,27 @@
+package formatter
+
+import (
+	"github.com/derkan/nlog"
+)
+
+// NewHooks returns hooks registered with nlog.RegisterHook by given names.
+// Names which are not registered are skipped.
+func NewHooks(names []string) []nlog.Hook {
+	var hooks []nlog.Hook
+	for _, name := range names {
+		if h, ok := nlog.LookupHook(name); ok {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// NewMarshallFn returns func registered with nlog.RegisterMarshallFn by given
+// name. Returns nil if name is empty or not registered.
+func NewMarshallFn(name string) nlog.MarshallFn {
+	if name == "" {
+		return nil
+	}
+	fn, _ := nlog.LookupMarshallFn(name)
+	return fn
+}
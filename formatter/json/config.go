@@ -17,7 +17,7 @@ type config struct {
 	// Level is logging level
 	Level nlog.Level `json:"level" yaml:"level"`
 	// NoPrintLevel if set level string will not be written
-	NoPrintLevel bool `json:"no_print_level" yaml:"print_level"`
+	NoPrintLevel bool `json:"no_print_level" yaml:"no_print_level"`
 	// Date sets whether to print date or not in layout 2006-01-02
 	Date bool `json:"date" yaml:"date"`
 	// Whether to print as string time, resolution can be set using TimeResolution
@@ -3,7 +3,6 @@ package json
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/derkan/nlog"
@@ -11,7 +10,6 @@ import (
 	"github.com/derkan/nlog/loader"
 	"github.com/derkan/nlog/pool"
 	"github.com/derkan/nlog/writer"
-	fl "github.com/derkan/nlog/writer/filerotater"
 )
 
 var (
@@ -45,6 +43,12 @@ func NewFormatter(opts ...option) *Formatter {
 	return c
 }
 
+func init() {
+	nlog.RegisterFormatter("json", func(cfg interface{}, appName string) (nlog.Formatter, error) {
+		return NewFromConfig(cfg.(loader.Formatter), appName), nil
+	})
+}
+
 // NewFromConfig builds a JSON formatter from given loader config
 // appName is used in syslog
 func NewFromConfig(f loader.Formatter, appName string) *Formatter {
@@ -59,50 +63,22 @@ func NewFromConfig(f loader.Formatter, appName string) *Formatter {
 		TimeResolution:     f.TimeResolution,
 		TimeUTC:            f.TimeUTC,
 		UnixTime:           f.UnixTime,
+		Hooks:              formatter.NewHooks(f.Hooks),
+		MarshallFn:         formatter.NewMarshallFn(f.MarshallFn),
 	}}
-
-	var parallelW []*writer.ParallelWriter
-	var normalW []*writer.Writer
-	var wrt io.WriteCloser
-	for _, w := range f.Writers {
-		switch w.Type {
-		case "stdout":
-			wrt = os.Stdout
-		case "stderr":
-			wrt = os.Stderr
-		case "syslog":
-			wrt = writer.NewSysLogWriter(appName)
-		case "filerotator":
-			wrt = &fl.Rotater{
-				Filename:   w.Filename,
-				Compress:   w.Compress,
-				MaxAge:     w.MaxAge,
-				LocalTime:  !w.UTC,
-				MaxBackups: w.MaxBackups,
-				MaxSize:    w.MaxSize,
-			}
-		default:
-			continue
-		}
-		if f.LeveledType == "parallel" {
-			parallelW = append(parallelW, writer.NewParellelWriter(wrt, w.Level, w.QueueLen))
-		} else {
-			normalW = append(normalW, writer.NewWriter(wrt, w.Level))
-		}
-	}
-	if len(normalW) > 0 {
-		c.cfg.Writer = writer.NewMultiWriter(normalW...)
-	}
-	if len(parallelW) > 0 {
-		c.cfg.Writer = writer.NewParallelMultiWriter(parallelW...)
+	if f.FileLocStrip != "" {
+		WithStripPath(f.FileLocStrip)(c.cfg)
 	}
+
+	c.cfg.Writer = formatter.NewMultiWriter(f, appName)
 	c.SetDefaults()
 	return c
 }
 
 func (cl *Formatter) SetDefaults() {
+	writer.SetJSONKeys(writer.JSONKeys{Time: TimeKey, Level: LevelKey, Logger: NameKey, Message: MsgKey})
 	if cl.cfg.Writer == nil {
-		cl.cfg.Writer = writer.NewMultiWriter(writer.NewWriter(os.Stderr, cl.cfg.Level))
+		cl.cfg.Writer = writer.NewMultiWriter(writer.NewWriter(writer.NewStdWriter(os.Stderr), cl.cfg.Level))
 	}
 	if cl.cfg.MarshallFn == nil {
 		cl.cfg.MarshallFn = json.Marshal
@@ -116,8 +92,14 @@ func (cl *Formatter) GetCallDepth(sub int) int {
 func (cl *Formatter) Init() {
 }
 
+// Writer returns writers of formatter
+func (cl *Formatter) Writer() writer.LeveledMultiWriter {
+	return cl.cfg.Writer
+}
+
 // Flush flushes to disk and closes writers
 func (cl *Formatter) Flush() {
+	cl.cfg.Writer.Sync()
 	cl.cfg.Writer.Close()
 }
 
@@ -0,0 +1,118 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+	"github.com/derkan/nlog/writer"
+)
+
+// sharedWriters are writers referred to by ref in configs by their key, see
+// sharedKey. They are shared by formatters of all loggers.
+var sharedWriters = struct {
+	sync.Mutex
+	m map[string]*sharedWriter
+}{m: make(map[string]*sharedWriter)}
+
+// sharedWriter is a writer shared by formatters, which is closed when all of
+// them close it
+type sharedWriter struct {
+	w    io.WriteCloser
+	key  string
+	refs int
+}
+
+// sharedRef is a reference of a formatter to a shared writer. It is a leveled
+// writer if shared writer is one, like file rotater.
+type sharedRef struct {
+	*sharedWriter
+	once sync.Once
+}
+
+// sharedKey returns key of writer w refers to, which is its name and
+// definition. Levels and queue length are set by each reference.
+func sharedKey(w loader.Writer) string {
+	w.LevelStr, w.Level = "", 0
+	w.MinLevelStr, w.MinLevel = "", 0
+	w.LevelsStr, w.Levels = nil, nil
+	w.QueueLen = 0
+	return fmt.Sprintf("%s %+v", w.Ref, w)
+}
+
+// newSharedWriter returns a reference to writer w refers to, building it if
+// it is not referred to by any formatter. Returns nil if writer can not be
+// built.
+func newSharedWriter(w loader.Writer, appName string) io.WriteCloser {
+	key := sharedKey(w)
+	sharedWriters.Lock()
+	defer sharedWriters.Unlock()
+	s, ok := sharedWriters.m[key]
+	if !ok {
+		wrt := NewWriter(w, appName)
+		if wrt == nil {
+			return nil
+		}
+		s = &sharedWriter{w: wrapWriter(w, wrt), key: key}
+		sharedWriters.m[key] = s
+	}
+	s.refs++
+	return &sharedRef{sharedWriter: s}
+}
+
+// Write implements io.Writer.
+func (r *sharedRef) Write(p []byte) (n int, err error) {
+	return r.w.Write(p)
+}
+
+// WriteIfLevel calls WriteIfLevel of shared writer if it is a leveled writer,
+// Write otherwise.
+func (r *sharedRef) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	if lw, ok := r.w.(writer.LeveledWriter); ok {
+		return lw.WriteIfLevel(lvl, p)
+	}
+	return r.w.Write(p)
+}
+
+// GetLevel returns level of shared writer if it is a leveled writer
+func (r *sharedRef) GetLevel() nlog.Level {
+	if lw, ok := r.w.(writer.LeveledWriter); ok {
+		return lw.GetLevel()
+	}
+	return nlog.DEBUG
+}
+
+// Enabled returns true if shared writer writes lines of lvl
+func (r *sharedRef) Enabled(lvl nlog.Level) bool {
+	if lw, ok := r.w.(writer.LeveledWriter); ok {
+		return lw.Enabled(lvl)
+	}
+	return true
+}
+
+// Sync calls Sync of shared writer if it implements writer.Syncer.
+func (r *sharedRef) Sync() error {
+	return writer.SyncWriter(r.w)
+}
+
+// Describe returns info of shared writer
+func (r *sharedRef) Describe() writer.Info {
+	return writer.Describe(r.w)
+}
+
+// Close releases reference, closing shared writer if it is the last one.
+// Further calls do nothing.
+func (r *sharedRef) Close() (err error) {
+	r.once.Do(func() {
+		sharedWriters.Lock()
+		defer sharedWriters.Unlock()
+		r.refs--
+		if r.refs == 0 {
+			delete(sharedWriters.m, r.key)
+			err = r.w.Close()
+		}
+	})
+	return err
+}
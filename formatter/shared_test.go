@@ -0,0 +1,70 @@
+package formatter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+	fl "github.com/derkan/nlog/writer/filerotater"
+)
+
+func TestSharedWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nlogshared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+	cfg, err := loader.FromContent(`
+log:
+  writers:
+    app:
+      type: filerotator
+      filename: `+filename+`
+  loggers:
+    http:
+      formatters:
+        - leveled: parallel
+          writers:
+            - ref: app
+              level: ERROR
+    db:
+      formatters:
+        - writers:
+            - ref: app
+`, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := NewMultiWriter(cfg.Loggers["http"].Formatters[0], "nlogtest")
+	db := NewMultiWriter(cfg.Loggers["db"].Formatters[0], "nlogtest")
+	if len(sharedWriters.m) != 1 {
+		t.Fatalf("expected one shared writer, got %d", len(sharedWriters.m))
+	}
+	var shared *sharedWriter
+	for _, s := range sharedWriters.m {
+		shared = s
+	}
+	if _, ok := shared.w.(*fl.Rotater); !ok || shared.refs != 2 {
+		t.Fatalf("expected rotater referred twice, got %T with %d refs", shared.w, shared.refs)
+	}
+	http.WriteIfLevel(nlog.ERROR, []byte("http\n"))
+	http.Close()
+	if shared.refs != 1 || len(sharedWriters.m) != 1 {
+		t.Fatalf("expected shared writer kept for db, got %d refs", shared.refs)
+	}
+	if _, err := db.WriteIfLevel(nlog.INFO, []byte("db\n")); err != nil {
+		t.Fatalf("expected writing after other reference closed, got %v", err)
+	}
+	db.Close()
+	if len(sharedWriters.m) != 0 {
+		t.Fatalf("expected shared writer closed with last reference, got %d", len(sharedWriters.m))
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil || string(b) != "http\ndb\n" {
+		t.Fatalf("expected lines of both loggers, got %q %v", b, err)
+	}
+}
@@ -0,0 +1,245 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+	"github.com/derkan/nlog/writer"
+	fl "github.com/derkan/nlog/writer/filerotater"
+	jd "github.com/derkan/nlog/writer/journald"
+	nw "github.com/derkan/nlog/writer/netwriter"
+	sl "github.com/derkan/nlog/writer/syslog"
+)
+
+// NewWriter builds the writer defined in given loader writer config.
+// appName is used in syslog. Returns nil if writer can not be built.
+func NewWriter(w loader.Writer, appName string) io.WriteCloser {
+	switch w.Type {
+	case "stdout":
+		return writer.NewStdWriter(os.Stdout)
+	case "stderr":
+		return writer.NewStdWriter(os.Stderr)
+	case "syslog":
+		return newSyslogWriter(w, appName)
+	case "filerotator":
+		r := &fl.Rotater{
+			Filename:       w.Filename,
+			Compress:       w.Compress,
+			MaxAge:         w.MaxAge,
+			LocalTime:      w.LocalTime,
+			MaxBackups:     w.MaxBackups,
+			MaxSize:        w.MaxSize,
+			RotateEvery:    w.RotateEvery,
+			BackupFormat:   w.BackupFormat,
+			BackupDir:      w.BackupDir,
+			Compression:    w.Compression,
+			CompressLevel:  w.CompressLevel,
+			MaxTotalSize:   w.MaxTotalSize,
+			WatchInterval:  w.WatchInterval,
+			SyncEvery:      w.SyncEvery,
+			SyncInterval:   w.SyncInterval,
+			SyncOnLevel:    w.SyncOnLevel,
+			SyncLevel:      w.SyncLevel,
+			DSync:          w.DSync,
+			MinFreeSpace:   w.MinFreeSpace,
+			DropOnLowSpace: w.DropOnLowSpace,
+			LowSpaceLevel:  w.LowSpaceLevel,
+		}
+		if fn, ok := lookupFunc(w.PostRotate).(func(string)); ok {
+			r.PostRotate = fn
+		}
+		if fn, ok := lookupFunc(w.OnCompressed).(func(string, string)); ok {
+			r.OnCompressed = fn
+		}
+		if fn, ok := lookupFunc(w.ErrorHandler).(func(error)); ok {
+			r.ErrorHandler = fn
+		}
+		if w.RotateOnSignal {
+			r.HandleSignals()
+		}
+		return r
+	case "route":
+		return newRouterWriter(w, appName)
+	case "journald":
+		identifier := w.Identifier
+		if identifier == "" {
+			identifier = appName
+		}
+		return jd.NewJournaldWriter(jd.WithSocket(w.Socket), jd.WithIdentifier(identifier))
+	case "tcp", "udp", "unix":
+		nwr := &nw.Writer{
+			Network:      w.Type,
+			Address:      w.Address,
+			Framing:      nw.ParseFraming(w.Framing),
+			DialTimeout:  w.DialTimeout,
+			WriteTimeout: w.WriteTimeout,
+			MinBackoff:   w.MinBackoff,
+			MaxBackoff:   w.MaxBackoff,
+			BufferSize:   w.BufferSize,
+		}
+		if w.TLS {
+			tlsCfg, err := nw.LoadTLSConfig(w.TLSCA, w.TLSCert, w.TLSKey, w.TLSSkipVerify)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid TLS config for %s writer %s, err: %v\n", w.Type, w.Address, err)
+				return nil
+			}
+			nwr.TLSConfig = tlsCfg
+		}
+		return nwr
+	}
+	if factory, ok := nlog.LookupWriter(w.Type); ok {
+		wrt, err := factory(w.Options, appName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config for %s writer, err: %v\n", w.Type, err)
+			return nil
+		}
+		return wrt
+	}
+	return nil
+}
+
+// lookupFunc returns func registered with nlog.RegisterFunc by name, nil if
+// name is empty or not registered
+func lookupFunc(name string) interface{} {
+	if name == "" {
+		return nil
+	}
+	fn, _ := nlog.LookupFunc(name)
+	return fn
+}
+
+// NewMultiWriter builds leveled writers of given loader formatter config.
+// appName is used in syslog. Returns nil if there is no valid writer.
+func NewMultiWriter(f loader.Formatter, appName string) writer.LeveledMultiWriter {
+	var parallelW []*writer.ParallelWriter
+	var normalW []*writer.Writer
+	for _, w := range f.Writers {
+		wrt := buildWriter(w, appName)
+		if wrt == nil {
+			continue
+		}
+		if f.LeveledType == "parallel" {
+			parallelW = append(parallelW, writer.NewParallelWriterLevels(wrt, levelSet(w), w.QueueLen))
+		} else {
+			normalW = append(normalW, writer.NewWriterLevels(wrt, levelSet(w)))
+		}
+	}
+	if len(parallelW) > 0 {
+		return writer.NewParallelMultiWriter(parallelW...)
+	}
+	if len(normalW) > 0 {
+		return writer.NewMultiWriter(normalW...)
+	}
+	return nil
+}
+
+// buildWriter builds writer of given loader writer config wrapped with
+// buffered and ring writers if they are enabled, or returns a reference to
+// shared writer it refers to. Returns nil if writer can not be built.
+func buildWriter(w loader.Writer, appName string) io.WriteCloser {
+	if w.Ref != "" {
+		return newSharedWriter(w, appName)
+	}
+	wrt := NewWriter(w, appName)
+	if wrt == nil {
+		return nil
+	}
+	return wrapWriter(w, wrt)
+}
+
+// levelSet returns levels written by given loader writer config. It is
+// empty if levels are given but none is valid, which Validate reports.
+func levelSet(w loader.Writer) writer.LevelSet {
+	if len(w.LevelsStr) > 0 || len(w.Levels) > 0 {
+		return writer.NewLevelSet(w.Levels...)
+	}
+	return writer.LevelRange(w.MinLevel, w.Level)
+}
+
+// wrapWriter wraps wrt with buffered and ring writers if they are enabled in
+// given loader writer config.
+func wrapWriter(w loader.Writer, wrt io.WriteCloser) io.WriteCloser {
+	if w.Buffered {
+		wrt = writer.NewBufferedWriter(wrt, w.FlushSize, w.FlushInterval, w.FlushLevel)
+	}
+	if w.RingSize > 0 {
+		ring := writer.NewRingWriter(wrt, w.RingLevel, w.RingSize, w.RingTrigger)
+		if w.RingSignal {
+			ring.DumpOnSignal()
+		}
+		if w.RingName != "" {
+			writer.RegisterRing(w.RingName, ring)
+		}
+		wrt = ring
+	}
+	return wrt
+}
+
+// newRouterWriter builds route writer of given loader writer config with
+// its routes. Returns nil if there is no valid route.
+func newRouterWriter(w loader.Writer, appName string) io.WriteCloser {
+	var routes []writer.Route
+	for _, rw := range w.Routes {
+		wrt := buildWriter(rw, appName)
+		if wrt == nil {
+			continue
+		}
+		route := writer.NewRoute(writer.NewWriterLevels(wrt, levelSet(rw)))
+		route.Loggers = rw.Loggers
+		if rw.Field != "" {
+			if rw.FieldValue != "" {
+				route.MatchField(rw.Field, rw.FieldValue)
+			} else {
+				route.MatchField(rw.Field)
+			}
+		}
+		route.Final = rw.Final
+		routes = append(routes, route)
+	}
+	if len(routes) == 0 {
+		return nil
+	}
+	return writer.NewRouterWriter(routes...)
+}
+
+// newSyslogWriter builds native syslog client for given loader writer config.
+func newSyslogWriter(w loader.Writer, appName string) io.WriteCloser {
+	format, err := sl.ParseFormat(w.SyslogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid syslog writer config, err: %v\n", err)
+		return nil
+	}
+	facility, err := sl.ParseFacility(w.Facility)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid syslog writer config, err: %v\n", err)
+		return nil
+	}
+	if w.AppName != "" {
+		appName = w.AppName
+	}
+	sw := sl.NewSyslogWriter(
+		sl.WithAddress(w.Network, w.Address),
+		sl.WithFormat(format),
+		sl.WithFacility(facility),
+		sl.WithHostname(w.Hostname),
+		sl.WithAppName(appName),
+		sl.WithProcID(w.ProcID),
+		sl.WithMsgID(w.MsgID),
+		sl.WithSDID(w.SDID),
+	)
+	if w.Framing != "" {
+		sw.Framing = nw.ParseFraming(w.Framing)
+	}
+	if w.TLS {
+		tlsCfg, err := nw.LoadTLSConfig(w.TLSCA, w.TLSCert, w.TLSKey, w.TLSSkipVerify)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid TLS config for syslog writer %s, err: %v\n", w.Address, err)
+			return nil
+		}
+		sw.TLSConfig = tlsCfg
+	}
+	return sw
+}
@@ -0,0 +1,43 @@
+package formatter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+	"github.com/derkan/nlog/writer"
+)
+
+func TestRingName(t *testing.T) {
+	cfg, err := loader.FromContent(`
+log:
+  formatters:
+    - leveled: parallel
+      writers:
+        - type: stderr
+          level: DEBUG
+          ring_size: 10
+          ring_name: nlogtest
+`, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := NewMultiWriter(cfg.Formatters[0], "nlogtest")
+	ring, ok := writer.LookupRing("nlogtest")
+	if !ok {
+		t.Fatal("expected ring registered by name")
+	}
+	mw.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	deadline := time.Now().Add(2 * time.Second)
+	for ring.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected debug line kept by ring of parallel writer")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mw.Close()
+	if _, ok := writer.LookupRing("nlogtest"); ok {
+		t.Fatal("expected ring unregistered on Close")
+	}
+}
@@ -1,6 +1,11 @@
 package loader
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/derkan/nlog"
@@ -26,7 +31,15 @@ var LevelCodes = map[string]nlog.Level{
 
 var FormatterTypes = []string{"console", "json"}
 var LeveledTypes = []string{"normal", "parallel"}
-var WriterTypes = []string{"stdout", "stderr", "syslog", "filerotator"}
+var WriterTypes = []string{"stdout", "stderr", "syslog", "filerotator", "tcp", "udp", "unix", "journald", "route"}
+var FramingTypes = []string{"newline", "octet"}
+var SyslogFormats = []string{"rfc5424", "rfc3164"}
+
+// ColorNames are names of colors of console formatters
+var ColorNames = []string{
+	"none", "black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"black_bold", "red_bold", "green_bold", "yellow_bold", "blue_bold", "magenta_bold", "cyan_bold", "white_bold",
+}
 
 type FileRotatorConfig struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
@@ -55,34 +68,250 @@ type FileRotatorConfig struct {
 	// time.
 	UTC bool `json:"utc" yaml:"utc"`
 
+	// LocalTime determines if local time is used in names of backup files and
+	// for time based rotation, overriding UTC.
+	LocalTime bool `json:"local_time" yaml:"local_time"`
+
 	// Compress determines if the rotated log files should be compressed
-	// using gzip. The default is not to perform compression.
+	// using Compression codec. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
+
+	// Compression is name of codec used for compression. Can be gzip, zlib,
+	// deflate or a codec registered with filerotater.RegisterCodec. Defaults
+	// to gzip.
+	Compression string `json:"compression" yaml:"compression"`
+
+	// CompressLevel is compression level of codec. Defaults to codec's default.
+	CompressLevel int `json:"compress_level" yaml:"compress_level"`
+
+	// MaxTotalSize is the maximum total size in megabytes of backup files,
+	// counting compressed sizes. The default is no limit.
+	MaxTotalSize int `json:"max_total_size" yaml:"max_total_size"`
+
+	// RotateEveryStr is period of time based rotation aligned to wall-clock
+	// boundaries. Can be hourly, daily, weekly or a duration like 15m or 6h.
+	// The default is to rotate only on size.
+	RotateEveryStr string `json:"rotate_every" yaml:"rotate_every"`
+	RotateEvery    time.Duration
+
+	// BackupFormat is name template of backup files with strftime style
+	// tokens and {name}, {ext}, {seq} placeholders. Defaults to
+	// {name}-%Y-%m-%dT%H-%M-%S.%L{ext}.
+	BackupFormat string `json:"backup_format" yaml:"backup_format"`
+
+	// BackupDir is directory template to move backups into, like
+	// archive/%Y/%m. Defaults to directory of Filename.
+	BackupDir string `json:"backup_dir" yaml:"backup_dir"`
+
+	// RotateOnSignal enables rotation on SIGHUP and SIGUSR1.
+	RotateOnSignal bool `json:"rotate_on_signal" yaml:"rotate_on_signal"`
+
+	// WatchInterval enables reopening Filename when it is moved or truncated
+	// externally, like by logrotate, checking it at most once per interval.
+	WatchInterval time.Duration `json:"watch_interval" yaml:"watch_interval"`
+
+	// SyncEvery fsyncs log file after every SyncEvery writes.
+	SyncEvery int `json:"sync_every" yaml:"sync_every"`
+
+	// SyncInterval fsyncs log file on first write after SyncInterval passed
+	// since last fsync.
+	SyncInterval time.Duration `json:"sync_interval" yaml:"sync_interval"`
+
+	// SyncLevelStr fsyncs log file after lines of this level or more severe
+	// levels, like ERROR. Empty disables it.
+	SyncLevelStr string `json:"sync_level" yaml:"sync_level"`
+	SyncLevel    nlog.Level
+	SyncOnLevel  bool
+
+	// DSync opens log file with O_DSYNC.
+	DSync bool `json:"dsync" yaml:"dsync"`
+
+	// MinFreeSpace deletes oldest backups when free space on filesystem of
+	// log file is less than this many megabytes.
+	MinFreeSpace int `json:"min_free_space" yaml:"min_free_space"`
+
+	// LowSpaceLevelStr drops lines less severe than this level while free
+	// space is below MinFreeSpace, like WARNING. Empty disables it.
+	LowSpaceLevelStr string `json:"low_space_level" yaml:"low_space_level"`
+	LowSpaceLevel    nlog.Level
+	DropOnLowSpace   bool
+
+	// PostRotate is name of a func(path string) registered with
+	// nlog.RegisterFunc, called with path of backup file after rotation.
+	PostRotate string `json:"post_rotate" yaml:"post_rotate"`
+
+	// OnCompressed is name of a func(src, dst string) registered with
+	// nlog.RegisterFunc, called after a backup file is compressed.
+	OnCompressed string `json:"on_compressed" yaml:"on_compressed"`
+
+	// ErrorHandler is name of a func(err error) registered with
+	// nlog.RegisterFunc, called with errors of background work.
+	ErrorHandler string `json:"error_handler" yaml:"error_handler"`
+}
+
+type NetWriterConfig struct {
+	// Address is remote address, host:port for tcp and udp writers or
+	// socket path for unix writers.
+	Address string `json:"address" yaml:"address"`
+
+	// Framing defines how messages are delimited on tcp and unix writers.
+	// Can be newline or octet (octet-counting). Defaults to newline.
+	Framing string `json:"framing" yaml:"framing"`
+
+	// TLS enables TLS for tcp writers.
+	TLS bool `json:"tls" yaml:"tls"`
+
+	// TLSSkipVerify disables server certificate verification.
+	TLSSkipVerify bool `json:"tls_skip_verify" yaml:"tls_skip_verify"`
+
+	// TLSCA is PEM file of CA certificates used to verify server.
+	TLSCA string `json:"tls_ca" yaml:"tls_ca"`
+
+	// TLSCert and TLSKey are PEM files of client certificate.
+	TLSCert string `json:"tls_cert" yaml:"tls_cert"`
+	TLSKey  string `json:"tls_key" yaml:"tls_key"`
+
+	// DialTimeout is maximum time to wait for a connection, like 5s.
+	DialTimeout time.Duration `json:"dial_timeout" yaml:"dial_timeout"`
+
+	// WriteTimeout is maximum time a single write may take, like 5s.
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout"`
+
+	// MinBackoff is minimum wait time between reconnection attempts.
+	MinBackoff time.Duration `json:"min_backoff" yaml:"min_backoff"`
+
+	// MaxBackoff is maximum wait time between reconnection attempts.
+	MaxBackoff time.Duration `json:"max_backoff" yaml:"max_backoff"`
+
+	// BufferSize is maximum number of bytes kept in memory while disconnected.
+	BufferSize int `json:"buffer_size" yaml:"buffer_size"`
+}
+
+type SyslogConfig struct {
+	// Network is network of syslog server, one of udp, tcp, unix or unixgram.
+	// Local syslog socket is used if Network and Address are not set.
+	Network string `json:"network" yaml:"network"`
+
+	// SyslogFormat is message format, rfc5424 or rfc3164. Defaults to rfc5424.
+	SyslogFormat string `json:"syslog_format" yaml:"syslog_format"`
+
+	// Facility is syslog facility name like user or local0. Defaults to user.
+	Facility string `json:"facility" yaml:"facility"`
+
+	// Hostname is HOSTNAME field. Defaults to os hostname.
+	Hostname string `json:"hostname" yaml:"hostname"`
+
+	// AppName is APP-NAME field. Defaults to application name.
+	AppName string `json:"app_name" yaml:"app_name"`
+
+	// ProcID is PROCID field. Defaults to process id.
+	ProcID string `json:"procid" yaml:"procid"`
+
+	// MsgID is MSGID field of rfc5424 messages.
+	MsgID string `json:"msgid" yaml:"msgid"`
+
+	// SDID is SD-ID used for log fields in rfc5424 messages.
+	SDID string `json:"sd_id" yaml:"sd_id"`
+}
+
+type JournaldConfig struct {
+	// Socket is path of journald socket. Defaults to /run/systemd/journal/socket.
+	Socket string `json:"socket" yaml:"socket"`
+
+	// Identifier is SYSLOG_IDENTIFIER field. Defaults to application name.
+	Identifier string `json:"identifier" yaml:"identifier"`
+}
+
+type RouteConfig struct {
+	// Routes are writers of a route writer. Each route writer gets lines of
+	// its level and more severe levels matching its route rules.
+	Routes []Writer `json:"routes" yaml:"routes"`
+
+	// Loggers routes only lines of sub loggers whose names start with one of
+	// these prefixes to this writer.
+	Loggers []string `json:"loggers" yaml:"loggers"`
+
+	// Field routes only lines having this field to this writer.
+	Field string `json:"field" yaml:"field"`
+
+	// FieldValue routes only lines whose Field has this value to this writer.
+	FieldValue string `json:"field_value" yaml:"field_value"`
+
+	// Final stops routing lines routed to this writer to following writers.
+	Final bool `json:"final" yaml:"final"`
 }
 
 // Writer is for final writers
 type Writer struct {
-	// Type is type of writer. Available options are syslog, stdout, stderr, filerotater
+	// Type is type of writer. Available options are syslog, stdout, stderr, filerotater,
+	// tcp, udp, unix, journald, route or a type registered with nlog.RegisterWriter
 	TypeStr string `json:"type" yaml:"type"`
 	Type    string
+	// Ref is name of a writer defined under writers key, which is shared by
+	// all formatters and loggers referring to it, like a rotated file. Other
+	// values are taken from its definition, except level, min_level, levels
+	// and queue_len.
+	Ref string `json:"ref" yaml:"ref"`
+	// Options are passed to factories of writer types registered with
+	// nlog.RegisterWriter
+	Options map[string]string `json:"options" yaml:"options"`
 	FileRotatorConfig
+	NetWriterConfig
+	SyslogConfig
+	JournaldConfig
+	RouteConfig
 	// QueueLen defines queue length for buffered writers.
-	// Only valid for Parallel writers which uses buffered channels
+	// Only valid for Parallel writers which uses buffered channels.
+	// Defaults to queue_len of formatter.
 	QueueLen int `json:"queue_len" yaml:"queue_len"`
-	// Level is logging level
+	// Buffered makes writer accumulate lines in memory and write them in batches
+	Buffered bool `json:"buffered" yaml:"buffered"`
+	// FlushSize is size of batches in bytes for buffered writers
+	FlushSize int `json:"flush_size" yaml:"flush_size"`
+	// FlushInterval is maximum time lines are kept in memory for buffered writers
+	FlushInterval time.Duration `json:"flush_interval" yaml:"flush_interval"`
+	// FlushLevelStr flushes buffered writers after lines of this level or more severe
+	FlushLevelStr string `json:"flush_level" yaml:"flush_level"`
+	FlushLevel    nlog.Level
+	// RingSize keeps this many lines less severe than RingLevel in memory,
+	// writing them only before lines of RingTrigger level. 0 disables it.
+	RingSize int `json:"ring_size" yaml:"ring_size"`
+	// RingLevelStr is the least severe level written directly by ring
+	// writers. Defaults to INFO.
+	RingLevelStr string `json:"ring_level" yaml:"ring_level"`
+	RingLevel    nlog.Level
+	// RingTriggerStr writes kept lines before lines of this level or more
+	// severe levels. Defaults to ERROR.
+	RingTriggerStr string `json:"ring_trigger" yaml:"ring_trigger"`
+	RingTrigger    nlog.Level
+	// RingSignal writes kept lines on SIGUSR2.
+	RingSignal bool `json:"ring_signal" yaml:"ring_signal"`
+	// RingName registers ring with writer.RegisterRing, so its kept lines can
+	// be served with ringhttp.NamedHandler.
+	RingName string `json:"ring_name" yaml:"ring_name"`
+	// Level is logging level, the least severe level written
 	LevelStr string `json:"level" yaml:"level"`
 	Level    nlog.Level
+	// MinLevelStr is the most severe level written, like WARNING to write
+	// only WARNING and INFO with level INFO. Defaults to FATAL.
+	MinLevelStr string `json:"min_level" yaml:"min_level"`
+	MinLevel    nlog.Level
+	// LevelsStr is an explicit list of levels written, overriding Level and
+	// MinLevel.
+	LevelsStr []string `json:"levels" yaml:"levels"`
+	Levels    []nlog.Level
 }
 
 type FormatterCommon struct {
-	// Type is type of formatter. Can be json or console
+	// Type is type of formatter. Can be json, console or a type registered with
+	// nlog.RegisterFormatter
 	TypeStr string `json:"type" yaml:"type"`
 	Type    string
 	// Level is logging level
 	LevelStr string `json:"level" yaml:"level"`
 	Level    nlog.Level
 	// NoPrintLevel if set level string will not be written
-	NoPrintLevel bool `json:"no_print_level" yaml:"print_level"`
+	NoPrintLevel bool `json:"no_print_level" yaml:"no_print_level"`
 	// Date sets whether to print date or not in layout 2006-01-02
 	Date bool `json:"date" yaml:"date"`
 	// Whether to print as string time, resolution can be set using TimeResolution
@@ -104,6 +333,14 @@ type FormatterCommon struct {
 	LeveledTypeStr string `json:"leveled" yaml:"leveled"`
 	// LeveledTypeStr defines how to call write method of writers. Can be one of normal or parallel
 	LeveledType string
+	// QueueLen is default queue length of writers of parallel formatters.
+	// Defaults to 1000.
+	QueueLen int `json:"queue_len" yaml:"queue_len"`
+	// Hooks are names of hooks registered with nlog.RegisterHook
+	Hooks []string `json:"hooks" yaml:"hooks"`
+	// MarshallFn is name of func registered with nlog.RegisterMarshallFn used to
+	// serialize interfaces. Defaults to json.Marshal
+	MarshallFn string `json:"marshall_fn" yaml:"marshall_fn"`
 }
 
 // Formatter holds common formatter configs
@@ -111,6 +348,12 @@ type Formatter struct {
 	FormatterCommon
 	// Colored determines whether to print in color only valid for console
 	Colored bool `json:"colored" yaml:"colored"`
+	// Options are passed to factories of formatter types registered with
+	// nlog.RegisterFormatter
+	Options map[string]string `json:"options" yaml:"options"`
+	// Colors are colors of levels for colored console formatters by level name,
+	// like ERROR: red_bold. See ColorNames.
+	Colors map[string]string `json:"colors" yaml:"colors"`
 	// Writers holds leveled writer config
 	Writers []Writer `json:"writers" yaml:"writers"`
 }
@@ -121,7 +364,21 @@ type Loader struct {
 	// Prefix adds prefix for each line log
 	Prefix string `json:"prefix" yaml:"prefix"`
 	// Formatters holds formatters
-	Formatters []Formatter `json:"console_formatters" yaml:"console_formatters"`
+	Formatters []Formatter `json:"formatters" yaml:"formatters"`
+	// Writers are writer definitions by name, which writers of formatters
+	// refer to with ref key. Their defaults are taken from root logger.
+	Writers map[string]Writer `json:"writers" yaml:"writers"`
+	// Loggers are named loggers, like http and db, configured like root
+	// logger with their own formatters. Their writers can refer to Writers.
+	Loggers map[string]*Loader `json:"loggers" yaml:"loggers"`
+	// base is base key of loaded config, used in paths of validation errors
+	base string
+	// loadErrs are errors found while loading, see Validate
+	loadErrs ValidationErrors
+	// warnings are environment variables matching no config key, see Warnings
+	warnings ValidationErrors
+	// shared are writer definitions of root logger for named loggers
+	shared map[string]Writer
 }
 
 func AsLevel(levelStr string, defaultValue nlog.Level) nlog.Level {
@@ -150,6 +407,27 @@ func AsDuration(durationStr string, defautValue time.Duration) time.Duration {
 	}
 }
 
+// AsRotateEvery returns rotation period for given string, which is one of
+// hourly, daily, weekly or a duration parsed by time.ParseDuration. Returns
+// 0 for empty or invalid strings.
+func AsRotateEvery(str string) time.Duration {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "":
+		return 0
+	case "hourly":
+		return time.Hour
+	case "daily":
+		return 24 * time.Hour
+	case "weekly":
+		return 7 * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
 // StrInSlice returns true if str is in list
 func StrInSlice(str string, list []string) bool {
 	for _, b := range list {
@@ -169,35 +447,116 @@ func CleanType(lst []string, typ string, defautlVal string) string {
 	return defautlVal
 }
 
+// formatterType returns typ if it is one of FormatterTypes or registered with
+// nlog.RegisterFormatter, defaultVal otherwise
+func formatterType(typ string, defaultVal string) string {
+	if _, ok := nlog.LookupFormatter(typ); ok {
+		return typ
+	}
+	return CleanType(FormatterTypes, typ, defaultVal)
+}
+
+// writerType returns typ if it is one of WriterTypes or registered with
+// nlog.RegisterWriter, defaultVal otherwise
+func writerType(typ string, defaultVal string) string {
+	if _, ok := nlog.LookupWriter(typ); ok {
+		return typ
+	}
+	return CleanType(WriterTypes, typ, defaultVal)
+}
+
 // FromContent loads yaml content from content.
 // baseKey is root key for logs if multiple root keys are existing
-func FromContent(content string, baseKey string) (*Loader, error) {
-	config, err := Config(content)
+func FromContent(content string, baseKey string, opts ...Option) (*Loader, error) {
+	return fromReader(strings.NewReader(content), Parse, baseKey, opts)
+}
+
+// FromJSON loads json content from content. Keys are same with yaml keys.
+// baseKey is root key for logs if multiple root keys are existing
+func FromJSON(content string, baseKey string, opts ...Option) (*Loader, error) {
+	return fromReader(strings.NewReader(content), ParseJSON, baseKey, opts)
+}
+
+// FromTOML loads toml content from content. Keys are same with yaml keys,
+// formatters and writers are arrays of tables.
+// baseKey is root key for logs if multiple root keys are existing
+func FromTOML(content string, baseKey string, opts ...Option) (*Loader, error) {
+	return fromReader(strings.NewReader(content), ParseTOML, baseKey, opts)
+}
+
+// parsers are config parsers by file extension, yaml is used for others
+var parsers = map[string]func(io.Reader) (Node, error){
+	".json": ParseJSON,
+	".toml": ParseTOML,
+}
+
+// FromFile loads config from filename. Format is detected by extension,
+// .json for json, .toml for toml and yaml for others.
+// baseKey is root key for logs if multiple root keys are existing
+func FromFile(filename string, baseKey string, opts ...Option) (*Loader, error) {
+	parse, ok := parsers[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		parse = Parse
+	}
+	fin, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
-	return fromCfg(config, baseKey), nil
+	defer fin.Close()
+	return fromReader(fin, parse, baseKey, opts)
 }
 
-// FromFile loads yaml content from filename.
-// baseKey is root key for logs if multiple root keys are existing
-func FromFile(filename string, baseKey string) (*Loader, error) {
-	config, err := ReadFile(filename)
+// fromReader loads config read from r with parse
+func fromReader(r io.Reader, parse func(io.Reader) (Node, error), baseKey string, opts []Option) (*Loader, error) {
+	cfg := &loadConfig{EnvPrefix: "NLOG_"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	root, err := parse(r)
 	if err != nil {
 		return nil, err
 	}
-	return fromCfg(config, baseKey), nil
+	l := fromCfg(&File{Root: root}, baseKey, cfg.EnvPrefix)
+	if cfg.Strict {
+		if errs := l.Validate(); errs != nil {
+			return nil, errs
+		}
+	}
+	return l, nil
 }
 
-func fromCfg(config *File, baseKey string) *Loader {
+// fromCfg loads config at baseKey of file. Values are overridden by
+// environment variables starting with envPrefix.
+func fromCfg(file *File, baseKey string, envPrefix string) *Loader {
 	if baseKey != "" {
 		baseKey += "."
 	}
-	l := new(Loader)
+	config := newReader(file, baseKey, envPrefix)
+	l := loaderFromCfg(config, baseKey, nil)
+	shared := l.Writers
+	if shared == nil {
+		shared = map[string]Writer{}
+	}
+	for _, name := range config.keys(baseKey + "loggers") {
+		if l.Loggers == nil {
+			l.Loggers = make(map[string]*Loader)
+		}
+		l.Loggers[name] = loaderFromCfg(config, baseKey+"loggers."+name+".", shared)
+	}
+	l.loadErrs = append(config.errs, config.unknownKeys(strings.TrimSuffix(baseKey, "."))...)
+	l.warnings = config.unknownEnvs()
+	return l
+}
+
+// loaderFromCfg loads logger config at baseKey, which is empty or ends with a
+// dot. Writer definitions are loaded if shared is nil, else writers refer to
+// shared.
+func loaderFromCfg(config *reader, baseKey string, shared map[string]Writer) *Loader {
+	l := &Loader{base: baseKey, shared: shared}
 	l.Prefix, _ = config.Get("", baseKey+"prefix")
 	l.TypeStr, _ = config.Get("", baseKey+"type")
-	l.Type = CleanType(FormatterTypes, l.TypeStr, "console")
-	l.LevelStr, _ = config.Get("stdout", baseKey+"level")
+	l.Type = formatterType(l.TypeStr, "console")
+	l.LevelStr, _ = config.Get("", baseKey+"level")
 	l.Level = AsLevel(l.LevelStr, nlog.DEBUG)
 	l.NoPrintLevel, _ = config.GetBool(false, baseKey+"no_print_level")
 	l.Date, _ = config.GetBool(true, baseKey+"date")
@@ -211,13 +570,26 @@ func fromCfg(config *File, baseKey string) *Loader {
 	l.FileLocCallerDepth, _ = config.GetInt(0, baseKey+"file_loc_caller_depth")
 	l.LeveledTypeStr, _ = config.Get("", baseKey+"leveled")
 	l.LeveledType = CleanType(LeveledTypes, l.LeveledTypeStr, "normal")
+	l.QueueLen, _ = config.GetInt(1000, baseKey+"queue_len")
+	l.Hooks = getList(config, baseKey+"hooks")
+	l.MarshallFn, _ = config.Get("", baseKey+"marshall_fn")
+
+	if shared == nil {
+		shared = make(map[string]Writer)
+		for _, name := range config.keys(baseKey + "writers") {
+			shared[name] = writerFromCfg(config, baseKey+"writers."+name+".", &Formatter{FormatterCommon: l.FormatterCommon}, nil)
+		}
+		if len(shared) > 0 {
+			l.Writers = shared
+		}
+	}
 
 	fmtCnt, _ := config.Count(baseKey + "formatters")
 	if fmtCnt > 0 {
 		l.Formatters = make([]Formatter, fmtCnt)
 		for i := 0; i < fmtCnt; i++ {
 			l.Formatters[i].TypeStr, _ = config.Get("", baseKey+"formatters[%d].type", i)
-			l.Formatters[i].Type = CleanType(FormatterTypes, l.Formatters[i].TypeStr, l.Type)
+			l.Formatters[i].Type = formatterType(l.Formatters[i].TypeStr, l.Type)
 			l.Formatters[i].LevelStr, _ = config.Get("", baseKey+"formatters[%d].level", i)
 			l.Formatters[i].Level = AsLevel(l.Formatters[i].LevelStr, l.Level)
 			l.Formatters[i].NoPrintLevel, _ = config.GetBool(l.NoPrintLevel, baseKey+"formatters[%d].no_print_level", i)
@@ -233,24 +605,181 @@ func fromCfg(config *File, baseKey string) *Loader {
 			l.Formatters[i].Colored, _ = config.GetBool(false, baseKey+"formatters[%d].colored", i)
 			l.Formatters[i].LeveledTypeStr, _ = config.Get("", baseKey+"formatters[%d].leveled", i)
 			l.Formatters[i].LeveledType = CleanType(LeveledTypes, l.Formatters[i].LeveledTypeStr, l.LeveledType)
+			l.Formatters[i].QueueLen, _ = config.GetInt(l.QueueLen, baseKey+"formatters[%d].queue_len", i)
+			l.Formatters[i].Hooks = getList(config, fmt.Sprintf(baseKey+"formatters[%d].hooks", i))
+			if l.Formatters[i].Hooks == nil {
+				l.Formatters[i].Hooks = l.Hooks
+			}
+			l.Formatters[i].MarshallFn, _ = config.Get(l.MarshallFn, baseKey+"formatters[%d].marshall_fn", i)
+			l.Formatters[i].Options = config.getMap(fmt.Sprintf(baseKey+"formatters[%d].options", i))
+			l.Formatters[i].Colors = config.getMap(fmt.Sprintf(baseKey+"formatters[%d].colors", i))
 			writerCnt, _ := config.Count(baseKey+"formatters[%d].writers", i)
 			if writerCnt > 0 {
 				l.Formatters[i].Writers = make([]Writer, writerCnt)
 				for j := 0; j < writerCnt; j++ {
-					l.Formatters[i].Writers[j].TypeStr, _ = config.Get("", baseKey+"formatters[%d].writers[%d].type", i, j)
-					l.Formatters[i].Writers[j].Type = CleanType(WriterTypes, l.Formatters[i].Writers[j].TypeStr, "stdout")
-					l.Formatters[i].Writers[j].LevelStr, _ = config.Get("", baseKey+"formatters[%d].writers[%d].level", i, j)
-					l.Formatters[i].Writers[j].Level = AsLevel(l.Formatters[i].Writers[j].LevelStr, l.Formatters[i].Level)
-					l.Formatters[i].Writers[j].Filename, _ = config.Get("", baseKey+"formatters[%d].writers[%d].filename", i, j)
-					l.Formatters[i].Writers[j].MaxSize, _ = config.GetInt(100, baseKey+"formatters[%d].writers[%d].max_size", i, j)
-					l.Formatters[i].Writers[j].MaxAge, _ = config.GetInt(0, baseKey+"formatters[%d].writers[%d].max_age", i, j)
-					l.Formatters[i].Writers[j].MaxBackups, _ = config.GetInt(0, baseKey+"formatters[%d].writers[%d].max_backups", i, j)
-					l.Formatters[i].Writers[j].UTC, _ = config.GetBool(l.Formatters[i].TimeUTC, baseKey+"formatters[%d].writers[%d].utc", i, j)
-					l.Formatters[i].Writers[j].Compress, _ = config.GetBool(true, baseKey+"formatters[%d].writers[%d].compress", i, j)
-					l.Formatters[i].Writers[j].QueueLen, _ = config.GetInt(1000, baseKey+"formatters[%d].writers[%d].queue_len", i, j)
+					l.Formatters[i].Writers[j] = writerFromCfg(config, fmt.Sprintf(baseKey+"formatters[%d].writers[%d].", i, j), &l.Formatters[i], shared)
 				}
 			}
 		}
 	}
 	return l
 }
+
+// getList returns items of list at key, or the value at key as a single item
+// if it is not a list. Overriding environment variable has comma separated
+// items.
+func getList(config *reader, key string) []string {
+	if s, ok := config.env(key); ok {
+		items := strings.Split(s, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		return items
+	}
+	var items []string
+	if cnt, err := config.Count(key); err == nil {
+		for k := 0; k < cnt; k++ {
+			if item, err := config.Get("", key+"[%d]", k); err == nil {
+				items = append(items, item)
+			}
+		}
+	} else if item, err := config.Get("", key); err == nil {
+		items = []string{item}
+	}
+	return items
+}
+
+// writerFromCfg loads writer config at key, which ends with a dot. Defaults
+// are taken from formatter f. Writers referring to shared writers with ref key
+// are loaded with refWriterFromCfg. shared is nil for writer definitions,
+// which can not refer to other writers.
+func writerFromCfg(config *reader, key string, f *Formatter, shared map[string]Writer) Writer {
+	if ref, _ := config.Get("", key+"ref"); ref != "" {
+		if shared != nil {
+			return refWriterFromCfg(config, key, ref, f, shared)
+		}
+		config.invalid(key+"ref", ref, "writer definitions can not refer to other writers")
+	}
+	var w Writer
+	w.TypeStr, _ = config.Get("", key+"type")
+	w.Type = writerType(w.TypeStr, "stdout")
+	w.Options = config.getMap(key + "options")
+	w.LevelStr, _ = config.Get("", key+"level")
+	w.Level = AsLevel(w.LevelStr, f.Level)
+	w.MinLevelStr, _ = config.Get("", key+"min_level")
+	w.MinLevel = AsLevel(w.MinLevelStr, nlog.FATAL)
+	w.LevelsStr = getList(config, key+"levels")
+	for _, lvl := range w.LevelsStr {
+		if v, ok := LevelCodes[lvl]; ok {
+			w.Levels = append(w.Levels, v)
+		}
+	}
+	w.Filename, _ = config.Get("", key+"filename")
+	w.MaxSize, _ = config.GetInt(100, key+"max_size")
+	w.MaxAge, _ = config.GetInt(0, key+"max_age")
+	w.MaxBackups, _ = config.GetInt(0, key+"max_backups")
+	w.UTC, _ = config.GetBool(f.TimeUTC, key+"utc")
+	w.LocalTime, _ = config.GetBool(!w.UTC, key+"local_time")
+	w.UTC = !w.LocalTime
+	w.Compress, _ = config.GetBool(true, key+"compress")
+	w.Compression, _ = config.Get("", key+"compression")
+	w.CompressLevel, _ = config.GetInt(0, key+"compress_level")
+	w.MaxTotalSize, _ = config.GetInt(0, key+"max_total_size")
+	w.RotateEveryStr, _ = config.Get("", key+"rotate_every")
+	w.RotateEvery = AsRotateEvery(w.RotateEveryStr)
+	w.BackupFormat, _ = config.Get("", key+"backup_format")
+	w.BackupDir, _ = config.Get("", key+"backup_dir")
+	w.RotateOnSignal, _ = config.GetBool(false, key+"rotate_on_signal")
+	w.WatchInterval, _ = config.GetDuration(0, key+"watch_interval")
+	w.SyncEvery, _ = config.GetInt(0, key+"sync_every")
+	w.SyncInterval, _ = config.GetDuration(0, key+"sync_interval")
+	w.SyncLevelStr, _ = config.Get("", key+"sync_level")
+	_, w.SyncOnLevel = LevelCodes[w.SyncLevelStr]
+	w.SyncLevel = AsLevel(w.SyncLevelStr, nlog.FATAL)
+	w.DSync, _ = config.GetBool(false, key+"dsync")
+	w.MinFreeSpace, _ = config.GetInt(0, key+"min_free_space")
+	w.LowSpaceLevelStr, _ = config.Get("", key+"low_space_level")
+	_, w.DropOnLowSpace = LevelCodes[w.LowSpaceLevelStr]
+	w.LowSpaceLevel = AsLevel(w.LowSpaceLevelStr, nlog.FATAL)
+	w.PostRotate, _ = config.Get("", key+"post_rotate")
+	w.OnCompressed, _ = config.Get("", key+"on_compressed")
+	w.ErrorHandler, _ = config.Get("", key+"error_handler")
+	w.QueueLen, _ = config.GetInt(f.QueueLen, key+"queue_len")
+	w.Buffered, _ = config.GetBool(false, key+"buffered")
+	w.FlushSize, _ = config.GetInt(0, key+"flush_size")
+	w.FlushInterval, _ = config.GetDuration(0, key+"flush_interval")
+	w.FlushLevelStr, _ = config.Get("", key+"flush_level")
+	w.FlushLevel = AsLevel(w.FlushLevelStr, nlog.ERROR)
+	w.RingSize, _ = config.GetInt(0, key+"ring_size")
+	w.RingLevelStr, _ = config.Get("", key+"ring_level")
+	w.RingLevel = AsLevel(w.RingLevelStr, nlog.INFO)
+	w.RingTriggerStr, _ = config.Get("", key+"ring_trigger")
+	w.RingTrigger = AsLevel(w.RingTriggerStr, nlog.ERROR)
+	w.RingSignal, _ = config.GetBool(false, key+"ring_signal")
+	w.RingName, _ = config.Get("", key+"ring_name")
+	w.Address, _ = config.Get("", key+"address")
+	w.Framing, _ = config.Get("", key+"framing")
+	config.oneOf(key+"framing", w.Framing, FramingTypes)
+	w.Framing = CleanType(FramingTypes, w.Framing, "")
+	w.TLS, _ = config.GetBool(false, key+"tls")
+	w.TLSSkipVerify, _ = config.GetBool(false, key+"tls_skip_verify")
+	w.TLSCA, _ = config.Get("", key+"tls_ca")
+	w.TLSCert, _ = config.Get("", key+"tls_cert")
+	w.TLSKey, _ = config.Get("", key+"tls_key")
+	w.DialTimeout, _ = config.GetDuration(0, key+"dial_timeout")
+	w.WriteTimeout, _ = config.GetDuration(0, key+"write_timeout")
+	w.MinBackoff, _ = config.GetDuration(0, key+"min_backoff")
+	w.MaxBackoff, _ = config.GetDuration(0, key+"max_backoff")
+	w.BufferSize, _ = config.GetInt(0, key+"buffer_size")
+	w.Network, _ = config.Get("", key+"network")
+	w.SyslogFormat, _ = config.Get("", key+"syslog_format")
+	config.oneOf(key+"syslog_format", w.SyslogFormat, SyslogFormats)
+	w.SyslogFormat = CleanType(SyslogFormats, w.SyslogFormat, "")
+	w.Facility, _ = config.Get("user", key+"facility")
+	w.Hostname, _ = config.Get("", key+"hostname")
+	w.AppName, _ = config.Get("", key+"app_name")
+	w.ProcID, _ = config.Get("", key+"procid")
+	w.MsgID, _ = config.Get("", key+"msgid")
+	w.SDID, _ = config.Get("", key+"sd_id")
+	w.Socket, _ = config.Get("", key+"socket")
+	w.Identifier, _ = config.Get("", key+"identifier")
+	w.Loggers = getList(config, key+"loggers")
+	w.Field, _ = config.Get("", key+"field")
+	w.FieldValue, _ = config.Get("", key+"field_value")
+	w.Final, _ = config.GetBool(false, key+"final")
+	routeCnt, _ := config.Count(key + "routes")
+	for k := 0; k < routeCnt; k++ {
+		w.Routes = append(w.Routes, writerFromCfg(config, fmt.Sprintf(key+"routes[%d].", k), f, shared))
+	}
+	return w
+}
+
+// refWriterFromCfg loads writer config at key referring to shared writer ref.
+// Levels and queue length are loaded from key, defaulting to ones of shared
+// writer and formatter f.
+func refWriterFromCfg(config *reader, key, ref string, f *Formatter, shared map[string]Writer) Writer {
+	w, ok := shared[ref]
+	if !ok {
+		w.Type = "stdout"
+	}
+	w.Ref = ref
+	if w.LevelStr == "" {
+		w.Level = f.Level
+	}
+	if lvl, _ := config.Get("", key+"level"); lvl != "" {
+		w.LevelStr, w.Level = lvl, AsLevel(lvl, w.Level)
+	}
+	if lvl, _ := config.Get("", key+"min_level"); lvl != "" {
+		w.MinLevelStr, w.MinLevel = lvl, AsLevel(lvl, w.MinLevel)
+	}
+	if lvls := getList(config, key+"levels"); lvls != nil {
+		w.LevelsStr, w.Levels = lvls, nil
+		for _, lvl := range lvls {
+			if v, ok := LevelCodes[lvl]; ok {
+				w.Levels = append(w.Levels, v)
+			}
+		}
+	}
+	w.QueueLen, _ = config.GetInt(f.QueueLen, key+"queue_len")
+	return w
+}
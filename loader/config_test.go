@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
+func TestFromFileFormats(t *testing.T) {
+	want, err := FromFile("../examples/yaml/all.yaml", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.Formatters) != 2 || len(want.Formatters[1].Writers) != 6 {
+		t.Fatalf("expected 2 formatters, got %+v", want.Formatters)
+	}
+	if tcp := want.Formatters[1].Writers[3]; tcp.MinBackoff != 100*time.Millisecond || tcp.MaxBackoff != 30*time.Second {
+		t.Fatalf("expected backoff of tcp writer, got %v %v", tcp.MinBackoff, tcp.MaxBackoff)
+	}
+	for _, filename := range []string{"../examples/json/all.json", "../examples/toml/all.toml"} {
+		got, err := FromFile(filename, "log")
+		if err != nil {
+			t.Fatalf("%s: %v", filename, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v, got %+v", filename, want, got)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	l, err := FromJSON(`{"level": "INFO", "formatters": [{"type": "json", "writers": [{"type": "stdout", "queue_len": 10}]}]}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Level != nlog.INFO || l.Formatters[0].Type != "json" || l.Formatters[0].Writers[0].QueueLen != 10 {
+		t.Fatalf("unexpected config %+v", l)
+	}
+	_, err = FromJSON("{\n  \"level\": INFO\n}", "")
+	if serr, ok := err.(*SyntaxError); !ok || serr.Format != "json" || serr.Line != 2 || serr.Column != 12 {
+		t.Fatalf("expected json syntax error at line 2, column 12, got %v", err)
+	}
+}
+
+func TestLoggers(t *testing.T) {
+	setenv(t, "NLOG_LOGGERS_DB_LEVEL", "WARNING")
+	doc := `
+log:
+  level: INFO
+  writers:
+    app:
+      type: filerotator
+      filename: /tmp/app.log
+      max_size: 5
+  formatters:
+    - writers:
+        - ref: app
+  loggers:
+    http:
+      formatters:
+        - type: json
+          writers:
+            - ref: app
+              level: ERROR
+            - type: stdout
+    db:
+      level: DEBUG
+      formatters:
+        - writers:
+            - ref: app
+              levels: [ERROR, WARNING]
+`
+	l, err := FromContent(doc, "log", WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Loggers) != 2 || l.Loggers["db"].Level != nlog.WARNING || l.Loggers["http"].Level != nlog.DEBUG {
+		t.Fatalf("expected named loggers with their levels, got %+v", l.Loggers)
+	}
+	root, http, db := l.Formatters[0].Writers[0], l.Loggers["http"].Formatters[0].Writers, l.Loggers["db"].Formatters[0].Writers[0]
+	if root.Ref != "app" || root.Type != "filerotator" || root.Filename != "/tmp/app.log" || root.MaxSize != 5 || root.Level != nlog.INFO {
+		t.Fatalf("expected shared writer definition, got %+v", root)
+	}
+	if http[0].Filename != "/tmp/app.log" || http[0].Level != nlog.ERROR || http[1].Type != "stdout" || len(db.Levels) != 2 {
+		t.Fatalf("expected levels set by references, got %+v %+v", http, db)
+	}
+	rendered, err := FromContent(l.Render(), "log", WithStrict())
+	if err != nil {
+		t.Fatalf("expected rendered config to load, got %v\n%s", err, l.Render())
+	}
+	if rendered.Render() != l.Render() {
+		t.Fatalf("expected same config after loading rendered one, got\n%s", rendered.Render())
+	}
+
+	os.Unsetenv("NLOG_LOGGERS_DB_LEVEL")
+	l, err = FromContent(`
+log:
+  writers:
+    app:
+      type: route
+      routes:
+        - ref: other
+  loggers:
+    http:
+      writers:
+        app:
+          type: stdout
+      formatters:
+        - writers:
+            - ref: ap
+`, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`log.writers.app.routes[0].ref: invalid value "other": writer definitions can not refer to other writers`,
+		`log.loggers.http.writers: unknown key`,
+		`log.loggers.http.formatters[0].writers[0].ref: invalid value "ap": unknown writer, define it under writers`,
+	}
+	errs := l.Validate()
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("expected %s, got %s", want[i], err)
+		}
+	}
+	if errs := l.Loggers["http"].Validate(); len(errs) != 1 || errs[0].Path != "log.loggers.http.formatters[0].writers[0].ref" {
+		t.Fatalf("expected named logger validated with shared writers, got %v", errs)
+	}
+}
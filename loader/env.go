@@ -0,0 +1,92 @@
+package loader
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// envNames converts config paths like formatters[0].level to environment
+// variable names like FORMATTERS_0_LEVEL
+var envNames = strings.NewReplacer(".", "_", "[", "_", "]", "")
+
+// env returns value of environment variable overriding value at spec, like
+// NLOG_FORMATTERS_0_LEVEL for log.formatters[0].level
+func (r *reader) env(spec string) (string, bool) {
+	if r.envPrefix == "" {
+		return "", false
+	}
+	r.read[spec] = true
+	name := r.envPrefix + strings.ToUpper(envNames.Replace(strings.TrimPrefix(spec, r.base)))
+	r.envs[name] = true
+	return os.LookupEnv(name)
+}
+
+// unknownEnvs returns warnings for environment variables with envPrefix which
+// do not override a config value, like misspelled ones or ones for missing
+// formatters and writers
+func (r *reader) unknownEnvs() ValidationErrors {
+	if r.envPrefix == "" {
+		return nil
+	}
+	var names []string
+	for _, kv := range os.Environ() {
+		name := kv[:strings.IndexByte(kv, '=')]
+		if strings.HasPrefix(name, r.envPrefix) && !r.envs[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	errs := make(ValidationErrors, len(names))
+	for i, name := range names {
+		errs[i] = &ValidationError{Path: name, Msg: "environment variable does not match a config key"}
+	}
+	return errs
+}
+
+// expandEnv replaces ${VAR} in s with value of environment variable VAR.
+// ${VAR:-default} uses default if VAR is unset or empty, ${VAR-default} only
+// if it is unset. $${ is kept as ${. False is returned if a ${ is not closed,
+// which is kept as is.
+func expandEnv(s string) (string, bool) {
+	if !strings.Contains(s, "${") {
+		return s, true
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), true
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			b.WriteString(s)
+			return b.String(), false
+		}
+		b.WriteString(s[:i])
+		b.WriteString(lookupVar(s[i+2 : i+end]))
+		s = s[i+end+1:]
+	}
+}
+
+// lookupVar returns value of VAR, VAR:-default or VAR-default expression
+func lookupVar(expr string) string {
+	name, def, orEmpty := expr, "", false
+	if i := strings.IndexByte(expr, '-'); i >= 0 {
+		name, def = expr[:i], expr[i+1:]
+		if strings.HasSuffix(name, ":") {
+			name, orEmpty = name[:len(name)-1], true
+		}
+	}
+	v, ok := os.LookupEnv(name)
+	if !ok || orEmpty && v == "" {
+		return def
+	}
+	return v
+}
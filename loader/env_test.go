@@ -0,0 +1,95 @@
+package loader
+
+import (
+	"os"
+	"testing"
+
+	"github.com/derkan/nlog"
+)
+
+// setenv sets environment variables until test completes
+func setenv(t *testing.T, kv ...string) {
+	for i := 0; i < len(kv); i += 2 {
+		name := kv[i]
+		os.Setenv(name, kv[i+1])
+		t.Cleanup(func() { os.Unsetenv(name) })
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	setenv(t, "NLOGTEST_SET", "set", "NLOGTEST_EMPTY", "")
+	tests := []struct {
+		in, out string
+		ok      bool
+	}{
+		{"plain", "plain", true},
+		{"${NLOGTEST_SET}", "set", true},
+		{"a-${NLOGTEST_SET}-${NLOGTEST_SET}", "a-set-set", true},
+		{"${NLOGTEST_UNSET}", "", true},
+		{"${NLOGTEST_UNSET:-INFO}", "INFO", true},
+		{"${NLOGTEST_EMPTY:-INFO}", "INFO", true},
+		{"${NLOGTEST_EMPTY-INFO}", "", true},
+		{"${NLOGTEST_UNSET-INFO}", "INFO", true},
+		{"${NLOGTEST_SET:-INFO}", "set", true},
+		{"$${NLOGTEST_SET}", "${NLOGTEST_SET}", true},
+		{"$HOME", "$HOME", true},
+		{"${NLOGTEST_SET", "${NLOGTEST_SET", false},
+	}
+	for _, tt := range tests {
+		if out, ok := expandEnv(tt.in); out != tt.out || ok != tt.ok {
+			t.Errorf("%q: expected %q %v, got %q %v", tt.in, tt.out, tt.ok, out, ok)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	setenv(t,
+		"NLOGTEST_LEVEL", "WARNING",
+		"NLOG_FORMATTERS_0_LEVEL", "DEBUG",
+		"NLOG_FORMATTERS_0_WRITERS_0_MAX_SIZE", "5",
+		"NLOG_FORMATTERS_0_WRITERS_0_LEVELS", "ERROR, WARNING",
+		"NLOG_PREFIX", "app",
+	)
+	doc := `
+log:
+  level: ${NLOGTEST_LEVEL:-INFO}
+  file_loc_caller_depth: ${NLOGTEST_UNSET:-2}
+  formatters:
+    - level: INFO
+      writers:
+        - type: filerotator
+          max_size: 10
+`
+	l, err := FromContent(doc, "log", WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := l.Formatters[0].Writers[0]
+	if l.Level != nlog.WARNING || l.FileLocCallerDepth != 2 || l.Prefix != "app" {
+		t.Fatalf("expected interpolated values and prefix override, got %+v", l.FormatterCommon)
+	}
+	if l.Formatters[0].Level != nlog.DEBUG || w.MaxSize != 5 || len(w.Levels) != 2 || w.Levels[1] != nlog.WARNING {
+		t.Fatalf("expected overridden values, got %+v", l.Formatters[0])
+	}
+	l, err = FromContent(doc, "log", WithEnvPrefix(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Formatters[0].Level != nlog.INFO || l.Formatters[0].Writers[0].MaxSize != 10 {
+		t.Fatalf("expected overrides disabled, got %+v", l.Formatters[0])
+	}
+
+	setenv(t, "NLOG_FORMATTERS_1_LEVEL", "DEBUG", "NLOG_CONFIG", "/etc/app/log.yaml")
+	l, err = FromContent(doc, "log", WithStrict())
+	if err != nil {
+		t.Fatalf("expected unknown variables not failing strict mode, got %v", err)
+	}
+	if w := l.Warnings(); len(w) != 2 || w[0].Path != "NLOG_CONFIG" || w[1].Path != "NLOG_FORMATTERS_1_LEVEL" {
+		t.Fatalf("expected unknown variables as warnings, got %v", w)
+	}
+	setenv(t, "NLOG_FORMATTERS_0_WRITERS_0_MAX_AGE", "old")
+	_, err = FromContent(doc, "log", WithStrict())
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Path != "log.formatters[0].writers[0].max_age" {
+		t.Fatalf("expected invalid overridden value error, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ParseJSON returns a root-level Node parsed from the JSON document read from
+// r. Numbers and booleans are kept as Scalars as written, nulls are nil.
+func ParseJSON(r io.Reader) (Node, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	var v interface{}
+	err = dec.Decode(&v)
+	switch e := err.(type) {
+	case nil:
+		if dec.More() {
+			pos := int(dec.InputOffset())
+			for pos < len(src) && bytes.IndexByte([]byte(" \t\r\n"), src[pos]) >= 0 {
+				pos++
+			}
+			return nil, jsonError(src, pos, "invalid character after top-level value")
+		}
+	case *json.SyntaxError:
+		return nil, jsonError(src, int(e.Offset)-1, e.Error())
+	default:
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, jsonError(src, len(src), "unexpected end of JSON input")
+		}
+		return nil, err
+	}
+	return jsonNode(v), nil
+}
+
+// jsonError returns a SyntaxError at offset pos of src
+func jsonError(src []byte, pos int, msg string) error {
+	if pos < 0 {
+		pos = 0
+	}
+	before := src[:pos]
+	return &SyntaxError{
+		Format: "json",
+		Line:   bytes.Count(before, []byte{'\n'}) + 1,
+		Column: pos - bytes.LastIndexByte(before, '\n'),
+		Msg:    msg,
+	}
+}
+
+// jsonNode converts a decoded JSON value to a Node
+func jsonNode(v interface{}) Node {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(Map, len(v))
+		for k, item := range v {
+			m[k] = jsonNode(item)
+		}
+		return m
+	case []interface{}:
+		l := make(List, len(v))
+		for i, item := range v {
+			l[i] = jsonNode(item)
+		}
+		return l
+	case nil:
+		return nil
+	}
+	return Scalar(fmt.Sprint(v))
+}
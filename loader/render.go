@@ -0,0 +1,173 @@
+package loader
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
+// trueDefaults are keys of bools defaulting to true, which are rendered also
+// when they are false
+var trueDefaults = map[string]bool{"date": true, "time": true, "compress": true}
+
+// levelNames are names levels are rendered with
+var levelNames = [...]string{
+	nlog.FATAL:   "FATAL",
+	nlog.ERROR:   "ERROR",
+	nlog.WARNING: "WARNING",
+	nlog.INFO:    "INFO",
+	nlog.DEBUG:   "DEBUG",
+}
+
+// refKeys are keys of writers referring to shared writers, see Writer.Ref
+var refKeys = map[string]bool{"ref": true, "level": true, "min_level": true, "levels": true, "queue_len": true}
+
+var (
+	levelType    = reflect.TypeOf(nlog.Level(0))
+	durationType = reflect.TypeOf(time.Duration(0))
+	rotatorType  = reflect.TypeOf(FileRotatorConfig{})
+)
+
+// Node returns config in effect as a node, after defaults and inheritance of
+// formatter values are applied. Levels are given by name and values which
+// are not set are omitted. Scalars hold raw values, they are quoted by Render.
+func (l *Loader) Node() Node {
+	return structNode(reflect.ValueOf(l).Elem())
+}
+
+// Render returns config in effect as a YAML document under base key it was
+// loaded from, which loads same config with FromContent.
+func (l *Loader) Render() string {
+	var node Node = l.Node()
+	keys := strings.Split(strings.TrimSuffix(l.base, "."), ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i] != "" {
+			node = Map{keys[i]: node}
+		}
+	}
+	return Render(quoted(node))
+}
+
+// MarshalYAML implements yaml.Marshaler of yaml packages, marshalling config
+// in effect like Render.
+func (l *Loader) MarshalYAML() (interface{}, error) {
+	return l.Node(), nil
+}
+
+// structNode returns fields of v having yaml tags by tag. Embedded structs
+// are inlined, file rotator fields only for filerotator writers. Values of
+// fields loaded from strings, like LevelStr, are taken from fields they are
+// converted to, like Level. Writers referring to shared writers have only
+// refKeys.
+func structNode(v reflect.Value) Map {
+	ref := v.FieldByName("Ref")
+	isRef := ref.IsValid() && ref.Kind() == reflect.String && ref.String() != ""
+	node := Map{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			if isRef || field.Type == rotatorType && v.FieldByName("Type").String() != "filerotator" {
+				continue
+			}
+			for key, value := range structNode(v.Field(i)) {
+				node[key] = value
+			}
+			continue
+		}
+		key := field.Tag.Get("yaml")
+		if key == "" || isRef && !refKeys[key] {
+			continue
+		}
+		value := v.Field(i)
+		set := false
+		if strings.HasSuffix(field.Name, "Str") {
+			set = value.Len() > 0
+			if converted := v.FieldByName(strings.TrimSuffix(field.Name, "Str")); converted.IsValid() {
+				value = converted
+			}
+		}
+		if !set && value.IsZero() && !(value.Kind() == reflect.Bool && trueDefaults[key]) {
+			continue
+		}
+		if key == "time_resolution" {
+			node[key] = Scalar(resolutionStr(time.Duration(value.Int())))
+			continue
+		}
+		node[key] = valueNode(value)
+	}
+	return node
+}
+
+// valueNode returns node of v
+func valueNode(v reflect.Value) Node {
+	switch {
+	case v.Type() == levelType:
+		return Scalar(levelNames[v.Int()])
+	case v.Type() == durationType:
+		return Scalar(time.Duration(v.Int()).String())
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		return valueNode(v.Elem())
+	case reflect.Struct:
+		return structNode(v)
+	case reflect.Slice:
+		list := make(List, v.Len())
+		for i := range list {
+			list[i] = valueNode(v.Index(i))
+		}
+		return list
+	case reflect.Map:
+		node := make(Map, v.Len())
+		for _, key := range v.MapKeys() {
+			node[key.String()] = valueNode(v.MapIndex(key))
+		}
+		return node
+	case reflect.Bool:
+		return Scalar(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Scalar(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Scalar(strconv.FormatUint(v.Uint(), 10))
+	}
+	return Scalar(v.String())
+}
+
+// quoted returns a copy of node with scalars quoted if they would not be
+// parsed as is
+func quoted(node Node) Node {
+	switch node := node.(type) {
+	case Map:
+		m := make(Map, len(node))
+		for key, value := range node {
+			m[key] = quoted(value)
+		}
+		return m
+	case List:
+		list := make(List, len(node))
+		for i, value := range node {
+			list[i] = quoted(value)
+		}
+		return list
+	case Scalar:
+		s := string(node)
+		if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "#:{}[],&*!|>'\"%@`\\") || strings.HasPrefix(s, "-") {
+			return Scalar(strconv.Quote(s))
+		}
+	}
+	return node
+}
+
+// resolutionStr returns one of TimeResolutions for time resolution d
+func resolutionStr(d time.Duration) string {
+	for _, name := range TimeResolutions {
+		if AsDuration(name, 0) == d {
+			return name
+		}
+	}
+	return "s"
+}
@@ -0,0 +1,68 @@
+package loader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	for _, filename := range []string{"../examples/yaml/all.yaml", "../examples/json/all.json", "../examples/toml/all.toml"} {
+		l, err := FromFile(filename, "log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := l.Render()
+		rendered, err := FromContent(doc, "log", WithStrict())
+		if err != nil {
+			t.Fatalf("%s: expected rendered config to load, got %v\n%s", filename, err, doc)
+		}
+		if again := rendered.Render(); again != doc {
+			t.Fatalf("%s: expected same config after loading rendered one, got\n%s\nwant\n%s", filename, again, doc)
+		}
+	}
+
+	l, err := FromContent(`
+app:
+  log:
+    level: WARNING
+    date: false
+    formatters:
+      - type: json
+        writers:
+          - type: stdout
+            levels: [ERROR, INFO]
+          - type: filerotator
+            filename: "/tmp/${NLOGTEST_UNSET:-app}.log"
+            rotate_every: daily
+          - type: tcp
+            address: "localhost:514"
+`, "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := l.Render()
+	// values are aligned by key length
+	flat := strings.Join(strings.Fields(doc), " ")
+	for _, want := range []string{
+		"app: log: date: false level: WARNING",
+		"formatters: - date: false level: WARNING",
+		"type: stdout levels: - ERROR - INFO",
+		"filename: /tmp/app.log",
+		"rotate_every: 24h0m0s",
+		`address: "localhost:514"`,
+	} {
+		if !strings.Contains(flat, want) {
+			t.Errorf("expected %q in rendered config:\n%s", want, doc)
+		}
+	}
+	if strings.Count(doc, "max_size") != 1 {
+		t.Errorf("expected file rotator values only for filerotator writer:\n%s", doc)
+	}
+	node, err := l.MarshalYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tcp := node.(Map)["formatters"].(List)[0].(Map)["writers"].(List)[2].(Map); tcp["address"] != Scalar("localhost:514") {
+		t.Errorf("expected raw values in marshalled node, got %v", tcp["address"])
+	}
+}
@@ -0,0 +1,372 @@
+package loader
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// ParseTOML returns a root-level Node parsed from the TOML document read from
+// r. Tables are parsed as Maps, arrays and arrays of tables as Lists. Values
+// are kept as Scalars, integers in decimal and others as written.
+//
+// Dotted keys, basic, literal and multi-line strings, arrays spanning lines
+// and inline tables are supported. Value types are not checked beyond syntax.
+func ParseTOML(r io.Reader) (node Node, err error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &tomlParser{parser: newParser(src, "toml"), root: make(Map)}
+	defer recoverError(&err)
+	p.parse()
+	return p.root, nil
+}
+
+// tomlParser parses TOML documents, reusing scanning methods of yaml parser
+type tomlParser struct {
+	*parser
+	root Map
+}
+
+func (p *tomlParser) parse() {
+	table := p.root
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return
+		}
+		if p.peek() == '[' {
+			table = p.parseTable()
+		} else {
+			p.parseKeyValue(table)
+		}
+		p.skipBlanks()
+		p.skipComment()
+		if !p.eol() {
+			p.failf(p.pos, "expected new line, found %q", p.peek())
+		}
+	}
+}
+
+// skipComment skips a comment at pos
+func (p *tomlParser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eol() {
+			p.pos++
+		}
+	}
+}
+
+// skipSpace skips blanks, line breaks and comments
+func (p *tomlParser) skipSpace() {
+	for {
+		p.skipBlanks()
+		p.skipComment()
+		if p.peek() != '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseTable parses a [table] or [[array of tables]] header and returns the
+// table following keys are set in
+func (p *tomlParser) parseTable() Map {
+	start := p.pos
+	array := p.at(p.pos+1) == '['
+	p.pos++
+	if array {
+		p.pos++
+	}
+	keys := p.parseKey()
+	if p.peek() != ']' || (array && p.at(p.pos+1) != ']') {
+		p.failf(p.pos, "expected ']' closing table header")
+	}
+	p.pos++
+	if array {
+		p.pos++
+	}
+	parent := p.root
+	for _, key := range keys[:len(keys)-1] {
+		parent = p.subTable(parent, key, start)
+	}
+	key := keys[len(keys)-1]
+	if !array {
+		return p.subTable(parent, key, start)
+	}
+	l, ok := parent[key].(List)
+	if _, exists := parent[key]; exists && !ok {
+		p.failf(start, "key %q is already defined", key)
+	}
+	table := make(Map)
+	parent[key] = append(l, table)
+	return table
+}
+
+// subTable returns table at key of m, creating it if it does not exist.
+// Last table of an array of tables is returned for arrays.
+func (p *tomlParser) subTable(m Map, key string, pos int) Map {
+	n, exists := m[key]
+	switch n := n.(type) {
+	case Map:
+		return n
+	case List:
+		if len(n) > 0 {
+			if table, ok := n[len(n)-1].(Map); ok {
+				return table
+			}
+		}
+	case nil:
+		if !exists {
+			table := make(Map)
+			m[key] = table
+			return table
+		}
+	}
+	p.failf(pos, "key %q is already defined", key)
+	return nil
+}
+
+// parseKey parses a dotted key
+func (p *tomlParser) parseKey() []string {
+	var keys []string
+	for {
+		p.skipBlanks()
+		start := p.pos
+		switch p.peek() {
+		case '"':
+			keys = append(keys, p.basicString())
+		case '\'':
+			keys = append(keys, p.literalString())
+		default:
+			for isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				p.failf(start, "expected key, found %q", p.peek())
+			}
+			keys = append(keys, string(p.src[start:p.pos]))
+		}
+		p.skipBlanks()
+		if p.peek() != '.' {
+			return keys
+		}
+		p.pos++
+	}
+}
+
+// parseKeyValue parses a key = value pair and sets it in table
+func (p *tomlParser) parseKeyValue(table Map) {
+	start := p.pos
+	keys := p.parseKey()
+	if p.peek() != '=' {
+		p.failf(p.pos, "expected '=' after key")
+	}
+	p.pos++
+	p.skipBlanks()
+	value := p.parseValue()
+	for _, key := range keys[:len(keys)-1] {
+		table = p.subTable(table, key, start)
+	}
+	key := keys[len(keys)-1]
+	if _, dup := table[key]; dup {
+		p.failf(start, "duplicate key %q", key)
+	}
+	table[key] = value
+}
+
+// parseValue parses a value at pos
+func (p *tomlParser) parseValue() Node {
+	start := p.pos
+	switch {
+	case bytes.HasPrefix(p.src[p.pos:], []byte(`"""`)):
+		return Scalar(p.multilineString('"'))
+	case bytes.HasPrefix(p.src[p.pos:], []byte(`'''`)):
+		return Scalar(p.multilineString('\''))
+	case p.peek() == '"':
+		return Scalar(p.basicString())
+	case p.peek() == '\'':
+		return Scalar(p.literalString())
+	case p.peek() == '[':
+		return p.parseArray()
+	case p.peek() == '{':
+		return p.parseInlineTable()
+	}
+	for c := p.peek(); !isEnd(c) && c != ',' && c != ']' && c != '}' && c != '#'; c = p.peek() {
+		p.pos++
+	}
+	// date and time can be separated by a space
+	if p.pos-start == 10 && p.peek() == ' ' && p.at(p.pos+1) >= '0' && p.at(p.pos+1) <= '9' {
+		p.pos++
+		for c := p.peek(); !isEnd(c) && c != ',' && c != ']' && c != '}' && c != '#'; c = p.peek() {
+			p.pos++
+		}
+	}
+	value := string(p.src[start:p.pos])
+	if value == "" {
+		p.failf(start, "expected value, found %q", p.peek())
+	}
+	return Scalar(p.bareValue(start, value))
+}
+
+// bareValue checks a boolean, number or date value, converting integers to
+// decimal
+func (p *tomlParser) bareValue(pos int, value string) string {
+	if value == "true" || value == "false" {
+		return value
+	}
+	digits := strings.Replace(value, "_", "", -1)
+	if i, err := strconv.ParseInt(digits, 0, 64); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if _, err := strconv.ParseFloat(digits, 64); err == nil {
+		return digits
+	}
+	if len(value) >= 8 && value[0] >= '0' && value[0] <= '9' && (value[4] == '-' || value[2] == ':') {
+		return value
+	}
+	p.failf(pos, "invalid value %q", value)
+	return ""
+}
+
+// basicString parses a double quoted string with escapes
+func (p *tomlParser) basicString() string {
+	start := p.pos
+	p.pos++
+	var b []byte
+	for {
+		switch c := p.peek(); {
+		case p.eol():
+			p.failf(start, "unterminated string")
+		case c == '"':
+			p.pos++
+			return string(b)
+		case c == '\\':
+			b = p.escape(b)
+		default:
+			b = append(b, c)
+			p.pos++
+		}
+	}
+}
+
+// literalString parses a single quoted string
+func (p *tomlParser) literalString() string {
+	start := p.pos
+	p.pos++
+	for p.peek() != '\'' {
+		if p.eol() {
+			p.failf(start, "unterminated string")
+		}
+		p.pos++
+	}
+	p.pos++
+	return string(p.src[start+1 : p.pos-1])
+}
+
+// multilineString parses a multi-line basic or literal string delimited by
+// three quotes
+func (p *tomlParser) multilineString(quote byte) string {
+	start := p.pos
+	delim := bytes.Repeat([]byte{quote}, 3)
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.pos++
+	}
+	var b []byte
+	for {
+		switch c := p.peek(); {
+		case p.pos >= len(p.src):
+			p.failf(start, "unterminated string")
+		case bytes.HasPrefix(p.src[p.pos:], delim):
+			// up to two quotes can end string
+			for i := 0; i < 2 && p.at(p.pos+3) == quote; i++ {
+				b = append(b, quote)
+				p.pos++
+			}
+			p.pos += 3
+			return string(b)
+		case c == '\\' && quote == '"':
+			// line ending backslash trims following white space
+			end := p.pos + 1
+			for isBlank(p.at(end)) {
+				end++
+			}
+			if p.at(end) != '\n' {
+				b = p.escape(b)
+				continue
+			}
+			p.pos = end
+			for isBlank(p.peek()) || p.peek() == '\n' {
+				p.pos++
+			}
+		default:
+			b = append(b, c)
+			p.pos++
+		}
+	}
+}
+
+// parseArray parses an array, which can span lines
+func (p *tomlParser) parseArray() List {
+	start := p.pos
+	p.pos++
+	l := List{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			p.failf(start, "expected ',' or ']'")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return l
+		}
+		l = append(l, p.parseValue())
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			if p.pos >= len(p.src) {
+				p.failf(start, "expected ',' or ']'")
+			}
+			p.failf(p.pos, "expected ',' or ']'")
+		}
+	}
+}
+
+// parseInlineTable parses an inline table on one line
+func (p *tomlParser) parseInlineTable() Map {
+	start := p.pos
+	p.pos++
+	m := make(Map)
+	p.skipBlanks()
+	if p.peek() == '}' {
+		p.pos++
+		return m
+	}
+	for {
+		p.parseKeyValue(m)
+		p.skipBlanks()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return m
+		default:
+			if p.eol() {
+				p.failf(start, "expected ',' or '}'")
+			}
+			p.failf(p.pos, "expected ',' or '}'")
+		}
+	}
+}
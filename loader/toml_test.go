@@ -0,0 +1,98 @@
+package loader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	node, err := ParseTOML(strings.NewReader(`
+# comment
+title = "nlog" # comment after value
+int = 1_000
+hex = 0xff
+float = 1.5e3
+bool = true
+date = 1979-05-27 07:32:00Z
+"quoted key" = 'C:\path'
+dotted.key = "escaped\t\"tab\" \u00e7"
+multi = """
+first \
+  second
+third"""
+literal = '''
+raw \n'''
+levels = [
+  "ERROR",   # first
+  "WARNING",
+]
+inline = {type = "stdout", level.name = "INFO"}
+
+[log]
+level = "DEBUG"
+
+[[log.formatters]]
+type = "console"
+
+[[log.formatters.writers]]
+type = "stdout"
+
+[[log.formatters]]
+type = "json"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Map{
+		"title":      Scalar("nlog"),
+		"int":        Scalar("1000"),
+		"hex":        Scalar("255"),
+		"float":      Scalar("1.5e3"),
+		"bool":       Scalar("true"),
+		"date":       Scalar("1979-05-27 07:32:00Z"),
+		"quoted key": Scalar(`C:\path`),
+		"dotted":     Map{"key": Scalar("escaped\t\"tab\" \u00e7")},
+		"multi":      Scalar("first second\nthird"),
+		"literal":    Scalar(`raw \n`),
+		"levels":     List{Scalar("ERROR"), Scalar("WARNING")},
+		"inline":     Map{"type": Scalar("stdout"), "level": Map{"name": Scalar("INFO")}},
+		"log": Map{
+			"level": Scalar("DEBUG"),
+			"formatters": List{
+				Map{"type": Scalar("console"), "writers": List{Map{"type": Scalar("stdout")}}},
+				Map{"type": Scalar("json")},
+			},
+		},
+	}
+	if !reflect.DeepEqual(node, want) {
+		t.Fatalf("expected %#v, got %#v", want, node)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		doc       string
+		line, col int
+		msg       string
+	}{
+		{"a = 1\na = 2\n", 2, 1, "duplicate key \"a\""},
+		{"a = \"open\n", 1, 5, "unterminated string"},
+		{"a = [1, 2\n", 1, 5, "expected ',' or ']'"},
+		{"a = yes\n", 1, 5, "invalid value \"yes\""},
+		{"a = 1 b = 2\n", 1, 7, "expected new line, found 'b'"},
+		{"[a\n", 1, 3, "expected ']' closing table header"},
+		{"a = 1\n[a]\n", 2, 1, "key \"a\" is already defined"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTOML(strings.NewReader(tt.doc))
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected syntax error, got %v", tt.doc, err)
+			continue
+		}
+		if serr.Format != "toml" || serr.Line != tt.line || serr.Column != tt.col || serr.Msg != tt.msg {
+			t.Errorf("%q: expected line %d, column %d: %s, got %v", tt.doc, tt.line, tt.col, tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/derkan/nlog"
+	fl "github.com/derkan/nlog/writer/filerotater"
+	sl "github.com/derkan/nlog/writer/syslog"
+)
+
+// TimeResolutions are valid time_resolution values
+var TimeResolutions = []string{"ns", "mcs", "mls", "s", "m", "h"}
+
+// ValidationError is an invalid value or an unknown key in config
+type ValidationError struct {
+	// Path is config path of value, like log.formatters[1].writers[0].type
+	Path string
+	// Value is the invalid value, empty for unknown keys
+	Value string
+	// Msg tells what is wrong and what is expected
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+	}
+	return fmt.Sprintf("%s: invalid value %q: %s", e.Path, e.Value, e.Msg)
+}
+
+// ValidationErrors are all errors of a config, returned by Loader.Validate and
+// by loading in strict mode
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid config, %d errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// loadConfig holds loading options
+type loadConfig struct {
+	// Strict fails loading if config is not valid
+	Strict bool
+	// EnvPrefix is prefix of environment variables overriding config values,
+	// NLOG_ by default. Overrides are disabled if it is empty.
+	EnvPrefix string
+}
+
+// Option is function type used for setting loading options
+type Option func(*loadConfig)
+
+// WithStrict makes loading fail with ValidationErrors if config is not valid,
+// instead of falling back to defaults. See Loader.Validate.
+func WithStrict() Option {
+	return func(c *loadConfig) {
+		c.Strict = true
+	}
+}
+
+// WithEnvPrefix sets prefix of environment variables overriding config
+// values, NLOG_ by default. Empty prefix disables overrides. Variables with
+// prefix matching no config key are reported by Loader.Warnings.
+func WithEnvPrefix(prefix string) Option {
+	return func(c *loadConfig) {
+		c.EnvPrefix = prefix
+	}
+}
+
+// Warnings returns environment variables with prefix of WithEnvPrefix which
+// do not override a config value, like misspelled ones or ones for missing
+// formatters and writers. They are not errors of Validate, as unrelated
+// variables may share prefix.
+func (l *Loader) Warnings() ValidationErrors {
+	return l.warnings
+}
+
+// Validate returns errors of config which are otherwise silently replaced by
+// defaults: unknown formatter and writer types, levels and other enumerated
+// values, values which are not a valid boolean, integer or duration and
+// unknown keys. Paths of errors include base key of config. Nil is returned
+// for a valid config.
+func (l *Loader) Validate() ValidationErrors {
+	v := &validator{errs: append(ValidationErrors(nil), l.loadErrs...), shared: l.Writers}
+	if l.shared != nil {
+		v.shared = l.shared
+	}
+	v.loader(l.base, l)
+	names := make([]string, 0, len(l.Writers))
+	for name := range l.Writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w := l.Writers[name]
+		v.writer(l.base+"writers."+name+".", &w)
+	}
+	names = names[:0]
+	for name := range l.Loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.loader(l.base+"loggers."+name+".", l.Loggers[name])
+	}
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// validator collects errors of config values
+type validator struct {
+	errs ValidationErrors
+	// shared are writer definitions writers refer to
+	shared map[string]Writer
+}
+
+// loader checks formatters of logger config at path
+func (v *validator) loader(path string, l *Loader) {
+	v.formatter(path, &l.FormatterCommon)
+	for i := range l.Formatters {
+		f := &l.Formatters[i]
+		fpath := fmt.Sprintf("%sformatters[%d].", path, i)
+		v.formatter(fpath, &f.FormatterCommon)
+		v.colors(fpath+"colors", f.Colors)
+		for j := range f.Writers {
+			v.writer(fmt.Sprintf("%swriters[%d].", fpath, j), &f.Writers[j])
+		}
+	}
+}
+
+func (v *validator) add(path, value, msg string) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Value: value, Msg: msg})
+}
+
+// oneOf checks value is empty or one of list
+func (v *validator) oneOf(path, value string, list []string) {
+	if value != "" && !StrInSlice(value, list) {
+		v.add(path, value, "want one of "+strings.Join(list, ", "))
+	}
+}
+
+// level checks value is empty or a level name
+func (v *validator) level(path, value string) {
+	if _, ok := LevelCodes[value]; value != "" && !ok {
+		v.add(path, value, "want one of FATAL, ERROR, WARNING, INFO, DEBUG")
+	}
+}
+
+func (v *validator) formatter(path string, f *FormatterCommon) {
+	if typ := firstSet(f.TypeStr, f.Type); typ != formatterType(typ, "") {
+		v.oneOf(path+"type", typ, FormatterTypes)
+	}
+	v.level(path+"level", f.LevelStr)
+	v.oneOf(path+"time_resolution", f.TimeResolutionStr, TimeResolutions)
+	v.oneOf(path+"leveled", firstSet(f.LeveledTypeStr, f.LeveledType), LeveledTypes)
+	for i, name := range f.Hooks {
+		if _, ok := nlog.LookupHook(name); !ok {
+			v.add(fmt.Sprintf("%shooks[%d]", path, i), name, "unknown hook, register it with nlog.RegisterHook")
+		}
+	}
+	if _, ok := nlog.LookupMarshallFn(f.MarshallFn); f.MarshallFn != "" && !ok {
+		v.add(path+"marshall_fn", f.MarshallFn, "unknown marshall fn, register it with nlog.RegisterMarshallFn")
+	}
+}
+
+// colors checks keys of colors are levels and values are color names
+func (v *validator) colors(path string, colors map[string]string) {
+	lvls := make([]string, 0, len(colors))
+	for lvl := range colors {
+		lvls = append(lvls, lvl)
+	}
+	sort.Strings(lvls)
+	for _, lvl := range lvls {
+		if _, ok := LevelCodes[lvl]; !ok {
+			v.add(path+"."+lvl, "", "unknown level, want one of FATAL, ERROR, WARNING, INFO, DEBUG")
+			continue
+		}
+		v.oneOf(path+"."+lvl, colors[lvl], ColorNames)
+	}
+}
+
+// fn checks name is empty or a func of type registered with nlog.RegisterFunc
+func (v *validator) fn(path, name string, ok func(fn interface{}) bool, typ string) {
+	if name == "" {
+		return
+	}
+	fn, found := nlog.LookupFunc(name)
+	if !found {
+		v.add(path, name, "unknown func, register it with nlog.RegisterFunc")
+	} else if !ok(fn) {
+		v.add(path, name, "registered func is not a "+typ)
+	}
+}
+
+func (v *validator) writer(path string, w *Writer) {
+	if w.Ref != "" {
+		if _, ok := v.shared[w.Ref]; !ok {
+			v.add(path+"ref", w.Ref, "unknown writer, define it under writers")
+		}
+		v.levels(path, w)
+		return
+	}
+	typ := firstSet(w.TypeStr, w.Type)
+	if typ != writerType(typ, "") {
+		v.oneOf(path+"type", typ, WriterTypes)
+	}
+	v.levels(path, w)
+	v.level(path+"sync_level", w.SyncLevelStr)
+	v.level(path+"low_space_level", w.LowSpaceLevelStr)
+	if lvl, ok := LevelCodes[w.LowSpaceLevelStr]; ok && lvl == nlog.FATAL {
+		v.add(path+"low_space_level", w.LowSpaceLevelStr, "want ERROR or a less severe level, FATAL selects WARNING")
+	}
+	v.level(path+"flush_level", w.FlushLevelStr)
+	v.level(path+"ring_level", w.RingLevelStr)
+	v.level(path+"ring_trigger", w.RingTriggerStr)
+	if w.RingName != "" && w.RingSize <= 0 {
+		v.add(path+"ring_name", w.RingName, "requires ring_size")
+	}
+	if w.RotateEveryStr != "" && AsRotateEvery(w.RotateEveryStr) == 0 {
+		v.add(path+"rotate_every", w.RotateEveryStr, "want hourly, daily, weekly or a duration like 15m")
+	}
+	switch typ {
+	case "filerotator":
+		if w.Compress {
+			if _, err := fl.LookupCodec(w.Compression); err != nil {
+				v.add(path+"compression", w.Compression, "unknown codec, want gzip, zlib, deflate or a registered codec")
+			}
+		}
+		v.fn(path+"post_rotate", w.PostRotate, func(fn interface{}) bool {
+			_, ok := fn.(func(string))
+			return ok
+		}, "func(path string)")
+		v.fn(path+"on_compressed", w.OnCompressed, func(fn interface{}) bool {
+			_, ok := fn.(func(string, string))
+			return ok
+		}, "func(src, dst string)")
+		v.fn(path+"error_handler", w.ErrorHandler, func(fn interface{}) bool {
+			_, ok := fn.(func(error))
+			return ok
+		}, "func(err error)")
+	case "tcp", "udp", "unix":
+		if w.Address == "" {
+			v.add(path+"address", "", "required for "+typ+" writer")
+		}
+	case "syslog":
+		if _, err := sl.ParseFacility(w.Facility); w.Facility != "" && err != nil {
+			v.add(path+"facility", w.Facility, "unknown syslog facility, like user or local0")
+		}
+	case "route":
+		if len(w.Routes) == 0 {
+			v.add(path+"routes", "", "required for route writer")
+		}
+		for i := range w.Routes {
+			v.writer(fmt.Sprintf("%sroutes[%d].", path, i), &w.Routes[i])
+		}
+	}
+}
+
+// levels checks levels written by writer
+func (v *validator) levels(path string, w *Writer) {
+	v.level(path+"level", w.LevelStr)
+	v.level(path+"min_level", w.MinLevelStr)
+	for i, lvl := range w.LevelsStr {
+		v.level(fmt.Sprintf("%slevels[%d]", path, i), lvl)
+	}
+	if len(w.LevelsStr) > 0 {
+		if len(w.Levels) == 0 {
+			v.add(path+"levels", "", "no valid level, no lines would be written")
+		}
+		return
+	}
+	// level may be inherited from formatter, so loaded levels are compared
+	if _, ok := LevelCodes[w.MinLevelStr]; ok && w.MinLevel > w.Level {
+		v.add(path+"min_level", w.MinLevelStr, "less severe than level "+levelNames[w.Level]+", no lines would be written")
+	}
+}
+
+// firstSet returns raw if it is set, else value. It lets Validate check
+// configs built in code, which set only cleaned values.
+func firstSet(raw, value string) string {
+	if raw != "" {
+		return raw
+	}
+	return value
+}
+
+// reader reads values of a config file like File, recording keys read and
+// values which can not be converted to requested type. Values are overridden
+// by environment variables and interpolated, see env.go.
+type reader struct {
+	*File
+	// base is base key of config, ending with a dot
+	base string
+	// envPrefix is prefix of overriding environment variables, empty
+	// disables overrides
+	envPrefix string
+	read      map[string]bool
+	// envs are names of environment variables looked up for overrides
+	envs map[string]bool
+	errs ValidationErrors
+}
+
+func newReader(f *File, base, envPrefix string) *reader {
+	return &reader{
+		File:      f,
+		base:      base,
+		envPrefix: envPrefix,
+		read:      make(map[string]bool),
+		envs:      make(map[string]bool),
+	}
+}
+
+// invalid records value at spec as invalid
+func (r *reader) invalid(spec, value, msg string) {
+	r.errs = append(r.errs, &ValidationError{Path: spec, Value: value, Msg: msg})
+}
+
+// oneOf records an error if value at spec is set but not in list
+func (r *reader) oneOf(spec, value string, list []string) {
+	if value != "" && !StrInSlice(value, list) {
+		r.invalid(spec, value, "want one of "+strings.Join(list, ", "))
+	}
+}
+
+// get returns value at spec, overridden by environment and interpolated.
+// Missing values are not errors.
+func (r *reader) get(def, spec string) (string, error) {
+	r.read[spec] = true
+	if s, ok := r.env(spec); ok {
+		return s, nil
+	}
+	s, err := r.File.Get(def, spec)
+	if _, ok := err.(*NodeTypeMismatch); ok {
+		r.errs = append(r.errs, &ValidationError{Path: spec, Msg: "want a value, found a list or map"})
+	}
+	if err != nil {
+		return s, err
+	}
+	v, ok := expandEnv(s)
+	if !ok {
+		r.invalid(spec, s, "unterminated ${ in value, use $${ for a literal ${")
+	}
+	if v == "" && v != s {
+		return def, nil
+	}
+	return v, nil
+}
+
+func (r *reader) Get(def string, spec string, params ...interface{}) (string, error) {
+	return r.get(def, fmt.Sprintf(spec, params...))
+}
+
+func (r *reader) GetInt(def int, spec string, params ...interface{}) (int, error) {
+	spec = fmt.Sprintf(spec, params...)
+	s, err := r.get("", spec)
+	if err != nil || s == "" {
+		return def, err
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		r.invalid(spec, s, "want an integer")
+		return def, err
+	}
+	return i, nil
+}
+
+func (r *reader) GetDuration(def time.Duration, spec string, params ...interface{}) (time.Duration, error) {
+	spec = fmt.Sprintf(spec, params...)
+	s, err := r.get("", spec)
+	if err != nil || s == "" {
+		return def, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		r.invalid(spec, s, "want a duration like 5s")
+		return def, err
+	}
+	return d, nil
+}
+
+func (r *reader) GetBool(def bool, spec string, params ...interface{}) (bool, error) {
+	spec = fmt.Sprintf(spec, params...)
+	s, err := r.get("", spec)
+	if err != nil || s == "" {
+		return def, err
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		r.invalid(spec, s, "want true or false")
+		return def, err
+	}
+	return b, nil
+}
+
+// keys returns sorted keys of map at spec
+func (r *reader) keys(spec string) []string {
+	r.read[spec] = true
+	node, err := Child(r.Root, spec)
+	if err != nil || node == nil {
+		return nil
+	}
+	m, ok := node.(Map)
+	if !ok {
+		r.errs = append(r.errs, &ValidationError{Path: spec, Msg: "want a map"})
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getMap returns values of map at spec by key, values which are not scalars
+// are skipped
+func (r *reader) getMap(spec string) map[string]string {
+	keys := r.keys(spec)
+	if keys == nil {
+		return nil
+	}
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if v, err := r.get("", spec+"."+k); err == nil {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+func (r *reader) Count(spec string, params ...interface{}) (int, error) {
+	spec = fmt.Sprintf(spec, params...)
+	r.read[spec] = true
+	return r.File.Count(spec)
+}
+
+// known returns true if key at path or a key under it is read
+func (r *reader) known(path string) bool {
+	if r.read[path] {
+		return true
+	}
+	for spec := range r.read {
+		if strings.HasPrefix(spec, path) && (spec[len(path)] == '.' || spec[len(path)] == '[') {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownKeys returns errors for keys under base which are never read, like
+// misspelled ones
+func (r *reader) unknownKeys(base string) ValidationErrors {
+	node, err := Child(r.Root, base)
+	if err != nil {
+		return nil
+	}
+	var errs ValidationErrors
+	var walk func(n Node, path string)
+	walk = func(n Node, path string) {
+		switch n := n.(type) {
+		case Map:
+			keys := make([]string, 0, len(n))
+			for k := range n {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				if !r.known(p) {
+					errs = append(errs, &ValidationError{Path: p, Msg: r.unknownMsg(path, k)})
+					continue
+				}
+				walk(n[k], p)
+			}
+		case List:
+			for i, item := range n {
+				walk(item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	walk(node, base)
+	return errs
+}
+
+// unknownMsg returns message for unknown key at path, suggesting the closest
+// known key
+func (r *reader) unknownMsg(path, key string) string {
+	prefix := ""
+	if path != "" {
+		prefix = path + "."
+	}
+	best, bestDist := "", 3
+	for spec := range r.read {
+		if !strings.HasPrefix(spec, prefix) || strings.ContainsAny(spec[len(prefix):], ".[") {
+			continue
+		}
+		name := spec[len(prefix):]
+		if d := distance(key, name); d < bestDist || d == bestDist && name < best {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return "unknown key"
+	}
+	return fmt.Sprintf("unknown key, did you mean %q?", best)
+}
+
+// distance returns edit distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
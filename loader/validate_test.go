@@ -0,0 +1,171 @@
+package loader
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/derkan/nlog"
+)
+
+func TestValidate(t *testing.T) {
+	l, err := FromContent(`
+log:
+  level: DEBG
+  time_resolution: s
+  formatters:
+    - type: consol
+      date: yes
+      writers:
+        - type: stdout
+          max_sise: 10
+          ring_name: app
+        - type: filerotator
+          queue_len: many
+          rotate_every: monthly
+          level: INFO
+          min_level: DEBUG
+          low_space_level: FATAL
+    - type: json
+      writers:
+        - type: route
+          routes:
+            - type: tcp
+              levels: [ERROR, EROR]
+              framing: lines
+other:
+  unrelated: key
+`, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`log.formatters[0].date: invalid value "yes": want true or false`,
+		`log.formatters[0].writers[1].queue_len: invalid value "many": want an integer`,
+		`log.formatters[1].writers[0].routes[0].framing: invalid value "lines": want one of newline, octet`,
+		`log.formatters[0].writers[0].max_sise: unknown key, did you mean "max_size"?`,
+		`log.level: invalid value "DEBG": want one of FATAL, ERROR, WARNING, INFO, DEBUG`,
+		`log.formatters[0].type: invalid value "consol": want one of console, json`,
+		`log.formatters[0].writers[0].ring_name: invalid value "app": requires ring_size`,
+		`log.formatters[0].writers[1].min_level: invalid value "DEBUG": less severe than level INFO, no lines would be written`,
+		`log.formatters[0].writers[1].low_space_level: invalid value "FATAL": want ERROR or a less severe level, FATAL selects WARNING`,
+		`log.formatters[0].writers[1].rotate_every: invalid value "monthly": want hourly, daily, weekly or a duration like 15m`,
+		`log.formatters[1].writers[0].routes[0].levels[1]: invalid value "EROR": want one of FATAL, ERROR, WARNING, INFO, DEBUG`,
+		`log.formatters[1].writers[0].routes[0].address: required for tcp writer`,
+	}
+	errs := l.Validate()
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("expected %s, got %s", want[i], err)
+		}
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	for _, filename := range []string{"../examples/yaml/all.yaml", "../examples/json/all.json", "../examples/toml/all.toml"} {
+		l, err := FromFile(filename, "log", WithStrict())
+		if err != nil {
+			t.Fatalf("%s: %v", filename, err)
+		}
+		if errs := l.Validate(); errs != nil {
+			t.Fatalf("%s: expected no errors, got %v", filename, errs)
+		}
+	}
+	l := &Loader{Formatters: []Formatter{{FormatterCommon: FormatterCommon{Type: "xml"}}}}
+	errs := l.Validate()
+	if len(errs) != 1 || errs[0].Path != "formatters[0].type" || errs[0].Value != "xml" {
+		t.Fatalf("expected formatter type error for config built in code, got %v", errs)
+	}
+}
+
+func TestValidateLevels(t *testing.T) {
+	l, err := FromContent(`
+log:
+  formatters:
+    - type: json
+      level: WARNING
+      writers:
+        - type: stdout
+          min_level: INFO
+        - type: stderr
+          levels: [EROR, WARN]
+`, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`log.formatters[0].writers[0].min_level: invalid value "INFO": less severe than level WARNING, no lines would be written`,
+		`log.formatters[0].writers[1].levels[0]: invalid value "EROR": want one of FATAL, ERROR, WARNING, INFO, DEBUG`,
+		`log.formatters[0].writers[1].levels[1]: invalid value "WARN": want one of FATAL, ERROR, WARNING, INFO, DEBUG`,
+		`log.formatters[0].writers[1].levels: no valid level, no lines would be written`,
+	}
+	errs := l.Validate()
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("expected %s, got %s", want[i], err)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	doc := `{"level": "INFO", "formatters": [{"writers": [{"type": "stdot"}]}]}`
+	if _, err := FromJSON(doc, ""); err != nil {
+		t.Fatalf("expected loading without strict mode, got %v", err)
+	}
+	_, err := FromJSON(doc, "", WithStrict())
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Path != "formatters[0].writers[0].type" || errs[0].Value != "stdot" {
+		t.Fatalf("expected writer type error, got %v", err)
+	}
+}
+
+func TestValidateRegistered(t *testing.T) {
+	nlog.RegisterWriter("nlogtest", func(opts map[string]string, appName string) (io.WriteCloser, error) {
+		return nil, nil
+	})
+	nlog.RegisterFunc("nlogtest.post_rotate", func(path string) {})
+	l, err := FromContent(`
+log:
+  formatters:
+    - type: console
+      hooks: [nlogtest.missing]
+      marshall_fn: nlogtest.missing
+      colors: {ERROR: red_bold, DEBG: blue, INFO: purple}
+      writers:
+        - type: nlogtest
+          options: {topic: app, brokers: "a:1,b:2"}
+        - type: filerotator
+          local_time: true
+          post_rotate: nlogtest.post_rotate
+          on_compressed: nlogtest.post_rotate
+`, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := l.Validate()
+	want := []string{
+		`log.formatters[0].hooks[0]: invalid value "nlogtest.missing": unknown hook, register it with nlog.RegisterHook`,
+		`log.formatters[0].marshall_fn: invalid value "nlogtest.missing": unknown marshall fn, register it with nlog.RegisterMarshallFn`,
+		`log.formatters[0].colors.DEBG: unknown level, want one of FATAL, ERROR, WARNING, INFO, DEBUG`,
+		`log.formatters[0].colors.INFO: invalid value "purple": want one of ` + strings.Join(ColorNames, ", "),
+		`log.formatters[0].writers[1].on_compressed: invalid value "nlogtest.post_rotate": registered func is not a func(src, dst string)`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("expected %s, got %s", want[i], err)
+		}
+	}
+	w := l.Formatters[0].Writers
+	if w[0].Options["brokers"] != "a:1,b:2" || w[0].Options["topic"] != "app" || !w[1].LocalTime || w[1].UTC {
+		t.Fatalf("expected writer options and local time, got %+v %+v", w[0], w[1])
+	}
+}
@@ -0,0 +1,53 @@
+package loader
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher polls a file for changes, see Watch
+type Watcher struct {
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Watch calls onChange from a new goroutine each time modification time or
+// size of filename changes, or it is replaced by renaming another file on it
+// as editors do. It is checked every interval. A missing file is not a
+// change, it is compared again when it appears.
+func Watch(filename string, interval time.Duration, onChange func()) *Watcher {
+	w := &Watcher{stop: make(chan struct{}), done: make(chan struct{})}
+	last, _ := os.Stat(filename)
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+			fi, err := os.Stat(filename)
+			if err != nil {
+				continue
+			}
+			if last == nil || !fi.ModTime().Equal(last.ModTime()) || fi.Size() != last.Size() || !os.SameFile(fi, last) {
+				last = fi
+				onChange()
+			}
+		}
+	}()
+	return w
+}
+
+// Close stops watching, waiting for a running onChange call to return
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	return nil
+}
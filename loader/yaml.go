@@ -1,131 +1,69 @@
 // // Assimilated From: "https://github.com/kylelemons/go-gypsy" by "7 of 9".  v2.1.x 08cad36 on  Nov 5, 2011
-// - changed parseNode for trimming spaces and '"'
+// - replaced line based parser with a recursive descent parser of a YAML subset
 
-// a simple configuration file, and as such does not support a lot of the more
-// nuanced syntaxes allowed in full-fledged YAML.  YAML does not allow indent with
-// tabs, and GYPSY does not ever consider a tab to be a space character.  It is
-// recommended that your editor be configured to convert tabs to spaces when
-// editing Gypsy config files.
+// YAML subset parser for configuration files. Nodes are kept as strings in
+// `yaml.Scalar`, lists of nodes in `yaml.List` and mappings in `yaml.Map`,
+// empty and null values are nil. Values are not typed while parsing, they are
+// converted by `File.GetInt`, `File.GetBool`, ... when read.
 //
-// Gypsy understands the following to be a list:
-//
-//     - one
-//     - two
-//     - three
-//
-// This is parsed as a `yaml.List`, and can be retrieved from the
-// `yaml.Node.List()` method.  In this case, each element of the `yaml.List` would
-// be a `yaml.Scalar` whose value can be retrieved with the `yaml.Scalar.String()`
-// method.
-//
-// Gypsy understands the following to be a mapping:
-//
-//     key:     value
-//     foo:     bar
-//     running: away
-//
-// A mapping is an unordered list of `key:value` pairs.  All whitespace after the
-// colon is stripped from the value and is used for alignment purposes during
-// export.  If the value is not a list or a map, everything after the first
-// non-space character until the end of the line is used as the `yaml.Scalar`
-// value.
-//
-// Gypsy allows arbitrary nesting of maps inside lists, lists inside of maps, and
-// maps and/or lists nested inside of themselves.
-//
-// A map inside of a list:
-//
-//     - name: John Smith
-//       age:  42
-//     - name: Jane Smith
-//       age:  45
-//
-// A list inside of a map:
+// Block lists and mappings are nested by indentation with spaces. Tabs can be
+// used as separators and in values, but not for indentation:
 //
 //     schools:
 //       - Meadow Glen
-//       - Forest Creek
-//       - Shady Grove
+//       - name: Forest Creek   # comments can follow values
+//         age:  42
 //     libraries:
-//       - Joseph Hollingsworth Memorial
-//       - Andrew Keriman Memorial
-//
-// A list of lists:
+//     - Joseph Hollingsworth Memorial
 //
-//     - - one
-//       - two
-//       - three
-//     - - un
-//       - deux
-//       - trois
-//     - - ichi
-//       - ni
-//       - san
+// Flow lists and mappings can be written inline, spanning lines:
 //
-// A map of maps:
+//     levels: [ERROR, WARNING]
+//     google: {company: "Google, Inc.", ticker: GOOG}
 //
-//     google:
-//       company: Google, Inc.
-//       ticker:  GOOG
-//       url:     http://google.com/
-//     yahoo:
-//       company: Yahoo, Inc.
-//       ticker:  YHOO
-//       url:     http://yahoo.com/
+// Scalars can be plain, single quoted('' is a quote) or double quoted with
+// escapes like \n, \t, \" and \u00e7. Plain and quoted scalars can span lines,
+// lines are joined with spaces and empty lines are kept as new lines.
 //
-// In the case of a map of maps, all sub-keys must be on subsequent lines and
-// indented equally.  It is allowable for the first key/value to be on the same
-// line if there is more than one key/value pair, but this is not recommended.
-//
-// Values can also be expressed in long form (leading whitespace of the first line
-// is removed from it and all subsequent lines).  In the normal (baz) case,
-// newlines are treated as spaces, all indentation is removed.  In the folded case
-// (bar), newlines are treated as spaces, except pairs of newlines (e.g. a blank
-// line) are treated as a single newline, only the indentation level of the first
-// line is removed, and newlines at the end of indented lines are preserved.  In
-// the verbatim (foo) case, only the indent at the level of the first line is
-// stripped.  The example:
+// Block scalars keep new lines with `|` or fold them with `>`, with `-` and
+// `+` chomping indicators for trailing new lines:
 //
 //     foo: |
 //       lorem ipsum dolor
 //       sit amet
-//     bar: >
+//     bar: >-
 //       lorem ipsum
+//       dolor sit amet
 //
-//         dolor
+// Nodes can be named with anchors and repeated with aliases, mappings can be
+// merged with `<<` key:
 //
-//       sit amet
-//     baz:
-//       lorem ipsum
-//        dolor sit amet
+//     base: &base
+//       level: INFO
+//       date:  true
+//     other:
+//       <<: *base
+//       level: DEBUG
+//
+// Tags are ignored, only one document is read and complex(`?`) keys are not
+// supported. Syntax errors are reported as `*yaml.SyntaxError` with line and
+// column.
 
 package loader
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// Supporting types and constants
-
-const (
-	typUnknown = iota
-	typSequence
-	typMapping
-	typScalar
-)
-
-var typNames = []string{
-	"Unknown", "Sequence", "Mapping", "Scalar",
-}
-
 // A Node is a YAML Node which can be a Map, List or Scalar.
 type Node interface {
 	write(io.Writer, int, int)
@@ -264,6 +202,21 @@ func (f *File) GetInt(def int, spec string, params ...interface{}) (int, error)
 	return i, nil
 }
 
+func (f *File) GetDuration(def time.Duration, spec string, params ...interface{}) (time.Duration, error) {
+	spec = fmt.Sprintf(spec, params...)
+	s, err := f.Get("", spec)
+	if err != nil {
+		return def, err
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || s == "" {
+		return def, err
+	}
+
+	return d, nil
+}
+
 func (f *File) GetBool(def bool, spec string, params ...interface{}) (bool, error) {
 	spec = fmt.Sprintf(spec, params...)
 	s, err := f.Get("", spec)
@@ -505,339 +458,861 @@ func Render(node Node) string {
 	return buf.String()
 }
 
-// Parse returns a root-level Node parsed from the lines read from r.  In
+// SyntaxError is returned by parsers for malformed documents
+type SyntaxError struct {
+	// Format is yaml, json or toml
+	Format string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: line %d, column %d: %s", e.Format, e.Line, e.Column, e.Msg)
+}
+
+// Parse returns a root-level Node parsed from the document read from r.  In
 // general, this will be done for you by one of the File constructors.
 func Parse(r io.Reader) (node Node, err error) {
-	lb := &lineBuffer{
-		Reader: bufio.NewReader(r),
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			switch r := r.(type) {
-			case error:
-				err = r
-			case string:
-				err = errors.New(r)
-			default:
-				err = fmt.Errorf("%v", r)
-			}
-		}
-	}()
-
-	node = parseNode(lb, 0, nil)
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := newParser(src, "yaml")
+	defer recoverError(&err)
+	node = p.parseDocument()
 	return
 }
 
-type lineReader interface {
-	Next(minIndent int) *indentedLine
+// recoverError sets err to error parsers panic with
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		switch r := r.(type) {
+		case error:
+			*err = r
+		case string:
+			*err = errors.New(r)
+		default:
+			*err = fmt.Errorf("%v", r)
+		}
+	}
 }
 
-type indentedLine struct {
-	lineno int
-	indent int
-	line   []byte
+// escapes are single character escapes of double quoted scalars
+var escapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028",
+	'P': "\u2029",
 }
 
-func (line *indentedLine) String() string {
-	return fmt.Sprintf("%2d: %s%s", line.indent,
-		strings.Repeat(" ", 0*line.indent), string(line.line))
+// parser is a recursive descent parser panicking with *SyntaxError
+type parser struct {
+	format string
+	src    []byte
+	pos int
+	// lines holds offsets of line starts
+	lines   []int
+	anchors map[string]Node
 }
 
-func parseNode(r lineReader, ind int, initial Node) (node Node) {
-	first := true
-	node = initial
+// merge is a `<<` key of a mapping
+type merge struct {
+	pos  int
+	node Node
+}
 
-	// read lines
-	for {
-		line := r.Next(ind)
-		if line == nil {
-			break
+func newParser(src []byte, format string) *parser {
+	src = bytes.TrimPrefix(src, []byte("\xef\xbb\xbf"))
+	src = bytes.Replace(src, []byte("\r\n"), []byte("\n"), -1)
+	src = bytes.Replace(src, []byte("\r"), []byte("\n"), -1)
+	p := &parser{format: format, src: src, lines: []int{0}, anchors: make(map[string]Node)}
+	for i, c := range src {
+		if c == '\n' {
+			p.lines = append(p.lines, i+1)
 		}
+	}
+	return p
+}
 
-		if len(line.line) == 0 {
-			continue
-		}
+// failf stops parsing with a SyntaxError at offset pos
+func (p *parser) failf(pos int, format string, args ...interface{}) {
+	line := p.line(pos)
+	panic(&SyntaxError{
+		Format: p.format,
+		Line:   line + 1,
+		Column: pos - p.lines[line] + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
 
-		if first {
-			ind = line.indent
-			first = false
-		}
+// line returns zero based line number of offset pos
+func (p *parser) line(pos int) int {
+	return sort.Search(len(p.lines), func(i int) bool { return p.lines[i] > pos }) - 1
+}
 
-		types := []int{}
-		pieces := []string{}
+// column returns zero based column of offset pos
+func (p *parser) column(pos int) int {
+	return pos - p.lines[p.line(pos)]
+}
 
-		var inlineValue func([]byte)
-		inlineValue = func(partial []byte) {
-			// TODO(kevlar): This can be a for loop now
-			vtyp, brk := getType(partial)
-			begin, end := partial[:brk], partial[brk:]
+func (p *parser) at(i int) byte {
+	if i >= 0 && i < len(p.src) {
+		return p.src[i]
+	}
+	return 0
+}
 
-			if vtyp == typMapping {
-				end = end[1:]
-			}
-			end = bytes.TrimLeft(end, " ")
-
-			switch vtyp {
-			case typScalar:
-				types = append(types, typScalar)
-				pieces = append(pieces, strings.Trim(strings.Trim(string(bytes.TrimSpace(end)), ","), "\""))
-				return
-			case typMapping:
-				types = append(types, typMapping)
-				pieces = append(pieces, strings.TrimSpace(string(begin)))
-
-				trimmed := bytes.TrimSpace(end)
-				if len(trimmed) == 1 && trimmed[0] == '|' {
-					text := ""
-
-					for {
-						l := r.Next(1)
-						if l == nil {
-							break
-						}
-
-						s := string(l.line)
-						s = strings.TrimSpace(s)
-						if len(s) == 0 {
-							break
-						}
-						text = text + "\n" + s
-					}
+func (p *parser) peek() byte {
+	return p.at(p.pos)
+}
 
-					types = append(types, typScalar)
-					pieces = append(pieces, string(text))
-					return
-				}
-				inlineValue(end)
-			case typSequence:
-				types = append(types, typSequence)
-				pieces = append(pieces, "-")
-				inlineValue(end)
-			}
-		}
+// eol returns true at a line break or end of input
+func (p *parser) eol() bool {
+	return p.pos >= len(p.src) || p.src[p.pos] == '\n'
+}
 
-		inlineValue(line.line)
-		var prev Node
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
 
-		// Nest inlines
-		for len(types) > 0 {
-			last := len(types) - 1
-			typ, piece := types[last], pieces[last]
+// isEnd returns true if c ends a token
+func isEnd(c byte) bool {
+	return c == 0 || c == ' ' || c == '\t' || c == '\n'
+}
 
-			var current Node
-			if last == 0 {
-				current = node
-			}
-			//child := parseNode(r, line.indent+1, typUnknown) // TODO allow scalar only
+func isFlowIndicator(c byte) bool {
+	return c == ',' || c == '[' || c == ']' || c == '{' || c == '}'
+}
 
-			// Add to current node
-			switch typ {
-			case typScalar: // last will be == nil
-				if _, ok := current.(Scalar); current != nil && !ok {
-					panic("cannot append scalar to non-scalar node")
-				}
-				if current != nil {
-					current = Scalar(piece) + " " + current.(Scalar)
-					break
-				}
-				current = Scalar(piece)
-			case typMapping:
-				var mapNode Map
-				var ok bool
-				var child Node
-
-				// Get the current map, if there is one
-				if mapNode, ok = current.(Map); current != nil && !ok {
-					_ = current.(Map) // panic
-				} else if current == nil {
-					mapNode = make(Map)
-				}
+// isNull returns true for plain scalars meaning null
+func isNull(s string) bool {
+	return s == "~" || s == "null" || s == "Null" || s == "NULL"
+}
 
-				if _, inlineMap := prev.(Scalar); inlineMap && last > 0 {
-					current = Map{
-						piece: prev,
-					}
-					break
-				}
+func (p *parser) skipBlanks() {
+	for isBlank(p.peek()) {
+		p.pos++
+	}
+}
 
-				child = parseNode(r, line.indent+1, prev)
-				mapNode[piece] = child
-				current = mapNode
+// skipComment skips rest of line if a comment starts at pos
+func (p *parser) skipComment() {
+	if p.peek() == '#' && isEnd(p.at(p.pos-1)) {
+		for !p.eol() {
+			p.pos++
+		}
+	}
+}
 
-			case typSequence:
-				var listNode List
-				var ok bool
-				var child Node
+// endOfLine fails unless only blanks and a comment are left on line
+func (p *parser) endOfLine() {
+	p.skipBlanks()
+	p.skipComment()
+	if !p.eol() {
+		p.failf(p.pos, "found unexpected character %q", p.peek())
+	}
+}
 
-				// Get the current list, if there is one
-				if listNode, ok = current.(List); current != nil && !ok {
-					_ = current.(List) // panic
-				} else if current == nil {
-					listNode = make(List, 0)
-				}
+// nextContent skips blanks, comments and empty lines. It returns column of
+// next content or -1 at end of input.
+func (p *parser) nextContent() int {
+	for {
+		if p.pos == 0 || p.at(p.pos-1) == '\n' {
+			p.skipIndent()
+		}
+		p.skipBlanks()
+		p.skipComment()
+		if !p.eol() {
+			return p.column(p.pos)
+		}
+		if p.pos >= len(p.src) {
+			return -1
+		}
+		p.pos++
+	}
+}
 
-				if _, inlineList := prev.(Scalar); inlineList && last > 0 {
-					current = List{
-						prev,
-					}
-					break
-				}
+// skipIndent skips spaces at line start, failing if a tab indents content
+func (p *parser) skipIndent() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+	if p.peek() == '\t' {
+		tab := p.pos
+		p.skipBlanks()
+		p.skipComment()
+		if !p.eol() {
+			p.failf(tab, "found tab character used as indentation")
+		}
+	}
+}
 
-				child = parseNode(r, line.indent+1, prev)
-				listNode = append(listNode, child)
-				current = listNode
+// isDocMarker returns true if a document start or end marker is at pos
+func (p *parser) isDocMarker() bool {
+	if p.column(p.pos) != 0 || p.pos+3 > len(p.src) {
+		return false
+	}
+	s := string(p.src[p.pos : p.pos+3])
+	return (s == "---" || s == "...") && isEnd(p.at(p.pos+3))
+}
 
-			}
+// isSeqEntry returns true if a block sequence entry starts at pos
+func (p *parser) isSeqEntry() bool {
+	return p.peek() == '-' && isEnd(p.at(p.pos+1))
+}
 
-			if last < 0 {
-				last = 0
+// isMappingKey returns true if a block mapping key starts at pos
+func (p *parser) isMappingKey() bool {
+	for i := p.pos; i < len(p.src) && p.src[i] != '\n'; i++ {
+		switch p.src[i] {
+		case ':':
+			if isEnd(p.at(i + 1)) {
+				return true
+			}
+		case '#':
+			if isBlank(p.at(i - 1)) {
+				return false
 			}
-			types = types[:last]
-			pieces = pieces[:last]
-			prev = current
 		}
-
-		node = prev
 	}
-	return
+	return false
 }
 
-func getType(line []byte) (typ, split int) {
-	if len(line) == 0 {
-		return
+func (p *parser) parseDocument() Node {
+	ind := p.nextContent()
+	for ind == 0 && p.peek() == '%' { // directives
+		for !p.eol() {
+			p.pos++
+		}
+		ind = p.nextContent()
+	}
+	var node Node
+	if ind == 0 && p.isDocMarker() && p.peek() == '-' {
+		p.pos += 3
+		node = p.parseBlock(-1, false)
+	} else if ind >= 0 && !p.isDocMarker() {
+		node = p.parseBlock(-1, false)
 	}
+	ind = p.nextContent()
+	if ind == 0 && p.isDocMarker() && p.peek() == '.' {
+		p.pos += 3
+		ind = p.nextContent()
+	}
+	if ind >= 0 {
+		if p.isDocMarker() {
+			p.failf(p.pos, "multiple documents are not supported")
+		}
+		p.failf(p.pos, "bad indentation")
+	}
+	return node
+}
 
-	if line[0] == '-' {
-		typ = typSequence
-		split = 1
-		return
+// parseBlock parses node following a mapping key, a sequence entry or
+// document start. Node must be indented more than parent, a sequence of a
+// mapping value can also be at parent indentation. It returns nil for empty
+// nodes.
+func (p *parser) parseBlock(parent int, mapValue bool) Node {
+	p.skipBlanks()
+	p.skipComment()
+	if !p.eol() {
+		return p.parseNode(parent, mapValue, mapValue)
 	}
+	ind := p.nextContent()
+	if ind < 0 || p.isDocMarker() {
+		return nil
+	}
+	if ind > parent || (mapValue && ind == parent && p.isSeqEntry()) {
+		return p.parseNode(parent, mapValue, false)
+	}
+	return nil
+}
 
-	typ = typScalar
+// parseNode parses node with its properties at pos. keyLine is true if node
+// is on same line with its mapping key.
+func (p *parser) parseNode(parent int, mapValue, keyLine bool) Node {
+	start := p.pos
+	anchor := p.readProperties()
+	if p.pos != start {
+		p.skipComment()
+		if p.eol() {
+			node := p.parseBlock(parent, mapValue)
+			p.setAnchor(anchor, node)
+			return node
+		}
+	}
+	node := p.parseContent(parent, keyLine)
+	p.setAnchor(anchor, node)
+	return node
+}
 
-	if line[0] == ' ' || line[0] == '"' {
-		return
+// readProperties reads anchor and tag of a node, tags are ignored
+func (p *parser) readProperties() (anchor string) {
+	for {
+		switch p.peek() {
+		case '&':
+			anchor = p.readName()
+		case '!':
+			for !isEnd(p.peek()) {
+				p.pos++
+			}
+		default:
+			return
+		}
+		p.skipBlanks()
 	}
+}
 
-	// the first character is real
-	// need to iterate past the first word
-	// things like "foo:" and "foo :" are mappings
-	// everything else is a scalar
+// readName reads name of an anchor or alias
+func (p *parser) readName() string {
+	p.pos++
+	start := p.pos
+	for c := p.peek(); !isEnd(c) && !isFlowIndicator(c); c = p.peek() {
+		p.pos++
+	}
+	if p.pos == start {
+		p.failf(start, "did not find expected anchor name")
+	}
+	return string(p.src[start:p.pos])
+}
 
-	idx := bytes.IndexAny(line, " \":")
-	if idx < 0 {
-		return
+func (p *parser) setAnchor(anchor string, node Node) {
+	if anchor != "" {
+		p.anchors[anchor] = node
 	}
+}
 
-	if line[idx] == '"' {
-		return
+// parseContent parses node at pos in block context
+func (p *parser) parseContent(parent int, keyLine bool) Node {
+	start := p.pos
+	switch c := p.peek(); {
+	case p.isSeqEntry():
+		if keyLine {
+			p.failf(start, "block sequence entries are not allowed in this context")
+		}
+		return p.parseSequence(p.column(start))
+	case c == '|' || c == '>':
+		return p.parseBlockScalar(parent)
+	case c == '*' || c == '[' || c == '{' || c == '"' || c == '\'':
+		node := p.parseFlowNode()
+		p.skipBlanks()
+		if p.peek() != ':' || !isEnd(p.at(p.pos+1)) {
+			p.endOfLine()
+			return node
+		}
+		if _, ok := node.(Scalar); !ok {
+			p.failf(start, "complex mapping keys are not supported")
+		}
+		p.pos = start
+	case c == '?' && isEnd(p.at(p.pos+1)):
+		p.failf(start, "complex mapping keys are not supported")
+	case isFlowIndicator(c) || c == '@' || c == '`':
+		p.failf(start, "found character %q that cannot start any token", c)
+	}
+	if p.isMappingKey() {
+		if keyLine {
+			p.failf(start, "mapping values are not allowed in this context")
+		}
+		return p.parseMapping(p.column(start))
 	}
+	return p.parsePlain(parent)
+}
 
-	if line[idx] == ':' {
-		typ = typMapping
-		split = idx
-	} else if line[idx] == ' ' {
-		// we have a space
-		// need to see if its all spaces until a :
-		for i := idx; i < len(line); i++ {
-			switch ch := line[i]; ch {
-			case ' ':
-				continue
-			case ':':
-				// only split on colons followed by a space
-				if i+1 < len(line) && line[i+1] != ' ' {
-					continue
-				}
+// parseMapping parses a block mapping with keys at column col
+func (p *parser) parseMapping(col int) Map {
+	m := make(Map)
+	var merges []merge
+	for {
+		start := p.pos
+		key := p.parseKey()
+		value := p.parseBlock(col, true)
+		if key == "<<" {
+			merges = append(merges, merge{pos: start, node: value})
+		} else if _, dup := m[key]; dup {
+			p.failf(start, "duplicate key %q", key)
+		} else {
+			m[key] = value
+		}
+		ind := p.nextContent()
+		if ind != col || p.isDocMarker() {
+			if ind > col {
+				p.failf(p.pos, "bad indentation of a mapping entry")
+			}
+			break
+		}
+	}
+	p.merge(m, merges)
+	return m
+}
 
-				typ = typMapping
-				split = i
-				break
-			default:
+// parseKey parses a block mapping key and following ':'
+func (p *parser) parseKey() string {
+	start := p.pos
+	var key string
+	switch p.peek() {
+	case '"':
+		key = p.parseDoubleQuoted()
+	case '\'':
+		key = p.parseSingleQuoted()
+	default:
+		for !p.eol() && !(p.peek() == ':' && isEnd(p.at(p.pos+1))) {
+			if p.peek() == '#' && isBlank(p.at(p.pos-1)) {
 				break
 			}
+			p.pos++
 		}
+		key = strings.TrimRight(string(p.src[start:p.pos]), " \t")
 	}
-
-	if typ == typMapping && split+1 < len(line) && line[split+1] != ' ' {
-		typ = typScalar
-		split = 0
+	p.skipBlanks()
+	if p.peek() != ':' || !isEnd(p.at(p.pos+1)) {
+		p.failf(p.pos, "could not find expected ':'")
 	}
+	p.pos++
+	return key
+}
 
-	return
+// merge adds keys of merged mappings which are not set in m
+func (p *parser) merge(m Map, merges []merge) {
+	for _, mg := range merges {
+		nodes := []Node{mg.node}
+		if l, ok := mg.node.(List); ok {
+			nodes = l
+		}
+		for _, n := range nodes {
+			mm, ok := n.(Map)
+			if !ok {
+				p.failf(mg.pos, "map merge requires map or sequence of maps as the value")
+			}
+			for k, v := range mm {
+				if _, ok := m[k]; !ok {
+					m[k] = v
+				}
+			}
+		}
+	}
 }
 
-// lineReader implementations
+// parseSequence parses a block sequence with entries at column col
+func (p *parser) parseSequence(col int) List {
+	l := List{}
+	for {
+		p.pos++ // '-'
+		l = append(l, p.parseBlock(col, false))
+		ind := p.nextContent()
+		if ind != col || !p.isSeqEntry() {
+			if ind > col {
+				p.failf(p.pos, "bad indentation of a sequence entry")
+			}
+			break
+		}
+	}
+	return l
+}
 
-type lineBuffer struct {
-	*bufio.Reader
-	readLines int
-	pending   *indentedLine
+// parsePlain parses a plain scalar in block context, continuing on lines
+// indented more than parent
+func (p *parser) parsePlain(parent int) Node {
+	text := p.plainLine()
+	multiline := false
+	for {
+		end, line := p.pos, p.line(p.pos)
+		ind := p.nextContent()
+		if ind <= parent || p.isDocMarker() {
+			p.pos = end
+			break
+		}
+		if p.isMappingKey() {
+			p.failf(p.pos, "mapping values are not allowed in this context")
+		}
+		if empty := p.line(p.pos) - line - 1; empty > 0 {
+			text += strings.Repeat("\n", empty)
+		} else {
+			text += " "
+		}
+		text += p.plainLine()
+		multiline = true
+	}
+	if !multiline && isNull(text) {
+		return nil
+	}
+	return Scalar(text)
 }
 
-func (lb *lineBuffer) Next(min int) (next *indentedLine) {
-	if lb.pending == nil {
-		var (
-			read []byte
-			more bool
-			err  error
-		)
+// plainLine reads a plain scalar until end of line or a comment
+func (p *parser) plainLine() string {
+	start := p.pos
+	for !p.eol() && !(p.peek() == '#' && isBlank(p.at(p.pos-1))) {
+		p.pos++
+	}
+	return strings.TrimRight(string(p.src[start:p.pos]), " \t")
+}
 
-		l := new(indentedLine)
-		l.lineno = lb.readLines
-		more = true
-		for more {
-			read, more, err = lb.ReadLine()
-			if err != nil {
-				if err == io.EOF {
-					return nil
-				}
-				panic(err)
+// parseBlockScalar parses a literal(|) or folded(>) block scalar
+func (p *parser) parseBlockScalar(parent int) Node {
+	folded := p.peek() == '>'
+	p.pos++
+	chomp, indent := byte(0), -1
+	for i := 0; i < 2; i++ {
+		switch c := p.peek(); {
+		case (c == '+' || c == '-') && chomp == 0:
+			chomp = c
+			p.pos++
+		case c >= '1' && c <= '9' && indent < 0:
+			indent = int(c - '0')
+			if parent > 0 {
+				indent += parent
 			}
-			l.line = append(l.line, read...)
+			p.pos++
 		}
-		lb.readLines++
+	}
+	if !isEnd(p.peek()) {
+		p.failf(p.pos, "did not find expected comment or line break")
+	}
+	p.endOfLine()
 
-		for _, ch := range l.line {
-			switch ch {
-			case ' ':
-				l.indent += 1
-				continue
-			default:
+	var lines []string
+	for p.pos < len(p.src) {
+		p.pos++ // line break
+		if p.pos >= len(p.src) {
+			break
+		}
+		start := p.pos
+		for p.peek() == ' ' {
+			p.pos++
+		}
+		n := p.pos - start
+		if p.eol() {
+			if indent >= 0 && n > indent {
+				lines = append(lines, strings.Repeat(" ", n-indent))
+			} else {
+				lines = append(lines, "")
 			}
+			continue
+		}
+		if indent < 0 && n > parent {
+			indent = n
+		}
+		if n < indent || indent < 0 {
+			p.pos = start - 1
 			break
 		}
-		l.line = l.line[l.indent:]
+		for !p.eol() {
+			p.pos++
+		}
+		lines = append(lines, string(p.src[start+indent:p.pos]))
+	}
 
-		// Ignore blank lines and comments.
-		if len(l.line) == 0 || l.line[0] == '#' {
-			return lb.Next(min)
+	n := len(lines)
+	for n > 0 && lines[n-1] == "" {
+		n--
+	}
+	text := joinBlockLines(lines[:n], folded)
+	switch {
+	case chomp == '-':
+	case chomp == '+':
+		text += strings.Repeat("\n", len(lines)-n)
+		if n > 0 {
+			text += "\n"
 		}
+	case n > 0:
+		text += "\n"
+	}
+	return Scalar(text)
+}
 
-		lb.pending = l
+// joinBlockLines joins lines of a block scalar. Folded lines are joined with
+// a space, except empty and more indented lines.
+func joinBlockLines(lines []string, folded bool) string {
+	var b strings.Builder
+	breaks, started, lastMore := 0, false, false
+	for _, l := range lines {
+		if l == "" {
+			breaks++
+			continue
+		}
+		more := isBlank(l[0])
+		switch {
+		case !started:
+			b.WriteString(strings.Repeat("\n", breaks))
+		case folded && !more && !lastMore && breaks == 0:
+			b.WriteByte(' ')
+		case folded && !more && !lastMore:
+			b.WriteString(strings.Repeat("\n", breaks))
+		default:
+			b.WriteString(strings.Repeat("\n", breaks+1))
+		}
+		b.WriteString(l)
+		started, lastMore, breaks = true, more, 0
 	}
-	next = lb.pending
-	if next.indent < min {
-		return nil
+	return b.String()
+}
+
+// parseDoubleQuoted parses a double quoted scalar with escapes
+func (p *parser) parseDoubleQuoted() string {
+	start := p.pos
+	p.pos++
+	var b []byte
+	for {
+		switch c := p.peek(); {
+		case p.pos >= len(p.src):
+			p.failf(start, "found unexpected end of stream while scanning a quoted scalar")
+		case c == '"':
+			p.pos++
+			return string(b)
+		case c == '\\' && p.at(p.pos+1) == '\n':
+			p.pos += 2
+			p.skipBlanks()
+		case c == '\\':
+			b = p.escape(b)
+		case c == '\n':
+			b = p.foldQuoted(b)
+		default:
+			b = append(b, c)
+			p.pos++
+		}
 	}
-	lb.pending = nil
-	return
 }
 
-type lineSlice []*indentedLine
+// escape appends character escaped at pos to b
+func (p *parser) escape(b []byte) []byte {
+	start := p.pos
+	c := p.at(p.pos + 1)
+	p.pos += 2
+	if s, ok := escapes[c]; ok {
+		return append(b, s...)
+	}
+	var n int
+	switch c {
+	case 'x':
+		n = 2
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		p.failf(start, "found unknown escape character %q", c)
+	}
+	if p.pos+n > len(p.src) {
+		p.failf(start, "did not find expected hexadecimal number")
+	}
+	v, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+	if err != nil {
+		p.failf(start, "did not find expected hexadecimal number")
+	}
+	p.pos += n
+	return append(b, string(rune(v))...)
+}
 
-func (ls *lineSlice) Next(min int) (next *indentedLine) {
-	if len(*ls) == 0 {
-		return nil
+// parseSingleQuoted parses a single quoted scalar
+func (p *parser) parseSingleQuoted() string {
+	start := p.pos
+	p.pos++
+	var b []byte
+	for {
+		switch c := p.peek(); {
+		case p.pos >= len(p.src):
+			p.failf(start, "found unexpected end of stream while scanning a quoted scalar")
+		case c == '\'' && p.at(p.pos+1) == '\'':
+			b = append(b, '\'')
+			p.pos += 2
+		case c == '\'':
+			p.pos++
+			return string(b)
+		case c == '\n':
+			b = p.foldQuoted(b)
+		default:
+			b = append(b, c)
+			p.pos++
+		}
+	}
+}
+
+// foldQuoted folds line break at pos in a quoted scalar to a space, or to
+// new lines if empty lines follow
+func (p *parser) foldQuoted(b []byte) []byte {
+	b = bytes.TrimRight(b, " \t")
+	breaks := 0
+	for p.peek() == '\n' {
+		p.pos++
+		p.skipBlanks()
+		breaks++
+	}
+	if breaks == 1 {
+		return append(b, ' ')
+	}
+	return append(b, bytes.Repeat([]byte{'\n'}, breaks-1)...)
+}
+
+// skipFlowSpace skips blanks, line breaks and comments in flow collections
+func (p *parser) skipFlowSpace() {
+	for {
+		p.skipBlanks()
+		p.skipComment()
+		if p.peek() != '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+// parseFlowItem parses a node with its properties in a flow collection
+func (p *parser) parseFlowItem() Node {
+	start := p.pos
+	anchor := p.readProperties()
+	var node Node
+	if p.pos != start {
+		p.skipFlowSpace()
+	}
+	if c := p.peek(); p.pos == start || !(c == ',' || c == ']' || c == '}') {
+		node = p.parseFlowNode()
 	}
-	next = (*ls)[0]
-	if next.indent < min {
+	p.setAnchor(anchor, node)
+	return node
+}
+
+// parseFlowNode parses an alias, a flow collection, a quoted or a flow plain
+// scalar
+func (p *parser) parseFlowNode() Node {
+	start := p.pos
+	switch p.peek() {
+	case '*':
+		name := p.readName()
+		node, ok := p.anchors[name]
+		if !ok {
+			p.failf(start, "found undefined alias %q", name)
+		}
+		return node
+	case '[':
+		return p.parseFlowSeq()
+	case '{':
+		return p.parseFlowMap()
+	case '"':
+		return Scalar(p.parseDoubleQuoted())
+	case '\'':
+		return Scalar(p.parseSingleQuoted())
+	}
+	return p.parseFlowPlain()
+}
+
+// parseFlowSeq parses a flow sequence, single pair mappings are allowed as
+// entries
+func (p *parser) parseFlowSeq() List {
+	start := p.pos
+	p.pos++
+	l := List{}
+	for {
+		p.skipFlowSpace()
+		if p.pos >= len(p.src) {
+			p.failf(start, "did not find expected ',' or ']'")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return l
+		}
+		keyPos := p.pos
+		item := p.parseFlowItem()
+		p.skipFlowSpace()
+		if p.peek() == ':' {
+			p.pos++
+			p.skipFlowSpace()
+			key := p.flowKey(keyPos, item)
+			item = Map{key: p.parseFlowValue(']')}
+			p.skipFlowSpace()
+		}
+		l = append(l, item)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			if p.pos >= len(p.src) {
+				p.failf(start, "did not find expected ',' or ']'")
+			}
+			p.failf(p.pos, "did not find expected ',' or ']'")
+		}
+	}
+}
+
+// parseFlowMap parses a flow mapping
+func (p *parser) parseFlowMap() Map {
+	start := p.pos
+	p.pos++
+	m := make(Map)
+	for {
+		p.skipFlowSpace()
+		if p.pos >= len(p.src) {
+			p.failf(start, "did not find expected ',' or '}'")
+		}
+		if p.peek() == '}' {
+			p.pos++
+			return m
+		}
+		keyPos := p.pos
+		key := p.flowKey(keyPos, p.parseFlowItem())
+		p.skipFlowSpace()
+		var value Node
+		if p.peek() == ':' {
+			p.pos++
+			p.skipFlowSpace()
+			value = p.parseFlowValue('}')
+			p.skipFlowSpace()
+		}
+		if _, dup := m[key]; dup {
+			p.failf(keyPos, "duplicate key %q", key)
+		}
+		m[key] = value
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			if p.pos >= len(p.src) {
+				p.failf(start, "did not find expected ',' or '}'")
+			}
+			p.failf(p.pos, "did not find expected ',' or '}'")
+		}
+	}
+}
+
+// flowKey returns key of a flow mapping entry
+func (p *parser) flowKey(pos int, node Node) string {
+	switch key := node.(type) {
+	case nil:
+		return ""
+	case Scalar:
+		return string(key)
+	}
+	p.failf(pos, "complex mapping keys are not supported")
+	return ""
+}
+
+// parseFlowValue parses value of a flow mapping entry, which can be empty
+func (p *parser) parseFlowValue(end byte) Node {
+	if c := p.peek(); c == ',' || c == end {
 		return nil
 	}
-	*ls = (*ls)[1:]
-	return
+	return p.parseFlowItem()
 }
 
-func (ls *lineSlice) Push(line *indentedLine) {
-	*ls = append(*ls, line)
+// parseFlowPlain parses a plain scalar in a flow collection
+func (p *parser) parseFlowPlain() Node {
+	start := p.pos
+	for !p.eol() {
+		c := p.peek()
+		if isFlowIndicator(c) || (c == '#' && isBlank(p.at(p.pos-1))) {
+			break
+		}
+		if c == ':' && (isEnd(p.at(p.pos+1)) || isFlowIndicator(p.at(p.pos+1))) {
+			break
+		}
+		p.pos++
+	}
+	text := strings.TrimRight(string(p.src[start:p.pos]), " \t")
+	if text == "" {
+		p.failf(start, "did not find expected node content")
+	}
+	if isNull(text) {
+		return nil
+	}
+	return Scalar(text)
 }
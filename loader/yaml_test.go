@@ -0,0 +1,221 @@
+package loader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, doc string) Node {
+	t.Helper()
+	node, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	return node
+}
+
+func TestParseBlock(t *testing.T) {
+	node := parse(t, `
+# comment
+log:
+  prefix:
+  level: DEBUG   # comment after value
+  address: 127.0.0.1:514
+  url: http://example.com/#anchor
+  tabbed:	value	with	tabs
+  formatters:
+  - type: console
+    writers:
+      - type: stdout
+      -
+        type: stderr
+  - - nested
+    - list
+  empty: ~
+`)
+	want := Map{"log": Map{
+		"prefix":  nil,
+		"level":   Scalar("DEBUG"),
+		"address": Scalar("127.0.0.1:514"),
+		"url":     Scalar("http://example.com/#anchor"),
+		"tabbed":  Scalar("value\twith\ttabs"),
+		"formatters": List{
+			Map{"type": Scalar("console"), "writers": List{
+				Map{"type": Scalar("stdout")},
+				Map{"type": Scalar("stderr")},
+			}},
+			List{Scalar("nested"), Scalar("list")},
+		},
+		"empty": nil,
+	}}
+	if !reflect.DeepEqual(node, want) {
+		t.Fatalf("expected %#v, got %#v", want, node)
+	}
+}
+
+func TestParseFlow(t *testing.T) {
+	node := parse(t, `
+levels: [ERROR, WARNING]
+empty: []
+map: {company: "Google, Inc.", ticker: GOOG, url: http://google.com/, nil}
+nested: [a, [b, c], {d: e}, f: g]
+multi: [
+  one,   # first
+  two,
+]
+json: {"a":1,"b":[true, null]}
+`)
+	want := Map{
+		"levels": List{Scalar("ERROR"), Scalar("WARNING")},
+		"empty":  List{},
+		"map": Map{
+			"company": Scalar("Google, Inc."),
+			"ticker":  Scalar("GOOG"),
+			"url":     Scalar("http://google.com/"),
+			"nil":     nil,
+		},
+		"nested": List{Scalar("a"), List{Scalar("b"), Scalar("c")}, Map{"d": Scalar("e")}, Map{"f": Scalar("g")}},
+		"multi":  List{Scalar("one"), Scalar("two")},
+		"json":   Map{"a": Scalar("1"), "b": List{Scalar("true"), nil}},
+	}
+	if !reflect.DeepEqual(node, want) {
+		t.Fatalf("expected %#v, got %#v", want, node)
+	}
+}
+
+func TestParseScalars(t *testing.T) {
+	node := parse(t, `
+double: "tab\there \"quoted\" \u00e7 \x41 # not comment"
+single: 'it''s # not comment'
+folded_double: "first
+  second
+
+  third"
+escaped_break: "no\
+  space"
+plain: first
+  second
+
+  third
+"quoted key": value
+literal: |
+  line 1
+    indented
+
+  line 3
+folded: >
+  folded
+  line
+
+  new paragraph
+    more indented
+  last
+strip: |-
+  text
+
+keep: |+
+  text
+
+explicit: |2
+    two spaces
+empty_block: |
+next: value
+`)
+	want := Map{
+		"double":        Scalar("tab\there \"quoted\" \u00e7 A # not comment"),
+		"single":        Scalar("it's # not comment"),
+		"folded_double": Scalar("first second\nthird"),
+		"escaped_break": Scalar("nospace"),
+		"plain":         Scalar("first second\nthird"),
+		"quoted key":    Scalar("value"),
+		"literal":       Scalar("line 1\n  indented\n\nline 3\n"),
+		"folded":        Scalar("folded line\nnew paragraph\n  more indented\nlast\n"),
+		"strip":         Scalar("text"),
+		"keep":          Scalar("text\n\n"),
+		"explicit":      Scalar("  two spaces\n"),
+		"empty_block":   Scalar(""),
+		"next":          Scalar("value"),
+	}
+	if !reflect.DeepEqual(node, want) {
+		t.Fatalf("expected %#v, got %#v", want, node)
+	}
+}
+
+func TestParseAnchors(t *testing.T) {
+	node := parse(t, `
+---
+base: &base
+  level: INFO
+  date: true
+levels: &levels [ERROR, WARNING]
+writer: !custom
+  <<: *base
+  level: DEBUG
+  levels: *levels
+merged:
+  <<: [*base, {time: false, date: false}]
+...
+`)
+	m := node.(Map)
+	want := Map{"level": Scalar("DEBUG"), "date": Scalar("true"), "levels": List{Scalar("ERROR"), Scalar("WARNING")}}
+	if !reflect.DeepEqual(m["writer"], want) {
+		t.Fatalf("expected %#v, got %#v", want, m["writer"])
+	}
+	want = Map{"level": Scalar("INFO"), "date": Scalar("true"), "time": Scalar("false")}
+	if !reflect.DeepEqual(m["merged"], want) {
+		t.Fatalf("expected %#v, got %#v", want, m["merged"])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		doc       string
+		line, col int
+		msg       string
+	}{
+		{"a: 1\n\tb: 2\n", 2, 1, "found tab character used as indentation"},
+		{"a:\n  b:\n    c: 1\n   d: 2\n", 4, 4, "bad indentation of a mapping entry"},
+		{"a: \"open\nb: 2\n", 1, 4, "found unexpected end of stream while scanning a quoted scalar"},
+		{"a: [1, 2\n", 1, 4, "did not find expected ',' or ']'"},
+		{"a: {b: 1 c: 2}\n", 1, 11, "did not find expected ',' or '}'"},
+		{"a: *missing\n", 1, 4, "found undefined alias \"missing\""},
+		{"a: 1\na: 2\n", 2, 1, "duplicate key \"a\""},
+		{"a: b: c\n", 1, 4, "mapping values are not allowed in this context"},
+		{"a: value\n  b: c\n", 2, 3, "mapping values are not allowed in this context"},
+		{"a: \"\\q\"\n", 1, 5, "found unknown escape character 'q'"},
+		{"a: [1] x\n", 1, 8, "found unexpected character 'x'"},
+		{"a: 1\n---\nb: 2\n", 2, 1, "multiple documents are not supported"},
+		{"- a\nb\n", 2, 1, "bad indentation"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.doc))
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected syntax error, got %v", tt.doc, err)
+			continue
+		}
+		if serr.Line != tt.line || serr.Column != tt.col || serr.Msg != tt.msg {
+			t.Errorf("%q: expected line %d, column %d: %s, got %v", tt.doc, tt.line, tt.col, tt.msg, err)
+		}
+	}
+}
+
+func TestReadExample(t *testing.T) {
+	f, err := ReadFile("../examples/yaml/all.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := f.Get("", "log.formatters[1].writers[1].backup_format"); s != "{name}.%Y%m%d.{seq}{ext}" {
+		t.Fatalf("expected quotes removed, got %q", s)
+	}
+	if s, _ := f.Get("", "log.formatters[1].writers[2].address"); s != "127.0.0.1:514" {
+		t.Fatalf("expected address, got %q", s)
+	}
+	if n, _ := f.Count("log.formatters[1].writers[5].routes[2].levels"); n != 2 {
+		t.Fatalf("expected 2 levels, got %d", n)
+	}
+	if s, err := f.Get("default", "log.prefix"); s != "default" || err == nil {
+		t.Fatalf("expected default for empty value, got %q %v", s, err)
+	}
+}
@@ -2,7 +2,6 @@ package log
 
 import (
 	"github.com/derkan/nlog"
-	"github.com/derkan/nlog/pool"
 )
 
 // config is for logger settings
@@ -20,8 +19,11 @@ type option func(*config)
 
 // Instance is logger instance
 type Instance struct {
-	cfg      *config
-	itemPool pool.ItemPool
+	cfg *config
+	// shared holds formatters, shared with sub loggers and replaced by Reload
+	shared *shared
+	// sub is true for sub loggers, which keep their prefix on Reload
+	sub bool
 }
 
 // WithPrefix sets Prefix for logger
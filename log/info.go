@@ -0,0 +1,60 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/formatter/console"
+	"github.com/derkan/nlog/formatter/json"
+	"github.com/derkan/nlog/writer"
+)
+
+// ConfigInfo describes config of a logger in effect, see Instance.Config
+type ConfigInfo struct {
+	// Level is the least severe level logged
+	Level string `json:"level" yaml:"level"`
+	// Prefix is prefix of lines
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Formatters are formatters lines are written with
+	Formatters []FormatterInfo `json:"formatters" yaml:"formatters"`
+}
+
+// FormatterInfo describes a formatter of a logger
+type FormatterInfo struct {
+	// Type is console, json or Go type name of other formatters
+	Type string `json:"type" yaml:"type"`
+	// Writer describes writers of formatter with levels they write. It is
+	// empty for formatters which don't have a Writer method.
+	Writer writer.Info `json:"writer" yaml:"writer"`
+}
+
+// Config returns level, prefix and formatters of logger in effect, with
+// writers of formatters and levels they write, like for diagnostics
+// endpoints. It reports config set by last Reload.
+func (ins *Instance) Config() ConfigInfo {
+	s := ins.load()
+	info := ConfigInfo{Level: nlog.LevelNames[s.MinLevel], Prefix: ins.prefix(s)}
+	for _, f := range s.formatters {
+		info.Formatters = append(info.Formatters, formatterInfo(f))
+	}
+	return info
+}
+
+// formatterInfo returns info of formatter f
+func formatterInfo(f nlog.Formatter) FormatterInfo {
+	var info FormatterInfo
+	switch f.(type) {
+	case *console.Formatter:
+		info.Type = "console"
+	case *json.Formatter:
+		info.Type = "json"
+	default:
+		info.Type = fmt.Sprintf("%T", f)
+	}
+	if wf, ok := f.(interface {
+		Writer() writer.LeveledMultiWriter
+	}); ok {
+		info.Writer = writer.Describe(wf.Writer())
+	}
+	return info
+}
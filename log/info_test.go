@@ -0,0 +1,80 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/derkan/nlog/loader"
+	"github.com/derkan/nlog/writer"
+)
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nlogconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+
+	cfg, err := loader.FromContent(`
+log:
+  level: INFO
+  prefix: app
+  formatters:
+    - type: console
+      writers:
+        - type: stderr
+          level: ERROR
+    - type: json
+      leveled: parallel
+      writers:
+        - type: route
+          routes:
+            - type: filerotator
+              filename: `+filename+`
+              min_level: WARNING
+              buffered: true
+              loggers: [http]
+              field: service
+              field_value: db
+`, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	defer logger.Flush()
+	want := ConfigInfo{
+		Level:  "INF",
+		Prefix: "app",
+		Formatters: []FormatterInfo{
+			{Type: "console", Writer: writer.Info{Type: "multi", Writers: []writer.Info{
+				{Type: "stderr", Levels: "FAT,ERR"},
+			}}},
+			{Type: "json", Writer: writer.Info{Type: "parallel", Writers: []writer.Info{
+				{Type: "route", Levels: "FAT,ERR,WRN,INF", Writers: []writer.Info{
+					{Type: "buffered", Levels: "WRN,INF", Loggers: []string{"http"}, Match: "service=db", Writers: []writer.Info{
+						{Type: "filerotator", Name: filename},
+					}},
+				}},
+			}}},
+		},
+	}
+	if got := logger.Config(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected config\n%+v\ngot\n%+v", want, got)
+	}
+
+	cfg, err = loader.FromContent(fileConfig("DEBUG", filename), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Reload(cfg, "nlogtest"); err != nil {
+		t.Fatal(err)
+	}
+	got := logger.Config()
+	if got.Level != "DBG" || len(got.Formatters) != 1 || got.Formatters[0].Writer.Writers[0].Name != filename {
+		t.Fatalf("expected reloaded config, got %+v", got)
+	}
+}
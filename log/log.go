@@ -9,9 +9,10 @@ func init() {
 	Logger = New()
 }
 
-// Flush closes all writers safely
+// Flush closes all writers of default logger and registered loggers safely
 func Flush() {
 	Logger.Flush()
+	flushLoggers()
 }
 
 // Fatal returns FATAL level logger item
@@ -5,10 +5,10 @@ import (
 	"os"
 
 	"github.com/derkan/nlog"
-	"github.com/derkan/nlog/formatter/console"
-	"github.com/derkan/nlog/formatter/json"
+	// console and json formatters are registered by their packages
+	_ "github.com/derkan/nlog/formatter/console"
+	_ "github.com/derkan/nlog/formatter/json"
 	"github.com/derkan/nlog/loader"
-	"github.com/derkan/nlog/pool"
 )
 
 // Init initalizes default logger
@@ -16,9 +16,13 @@ func Init(opts ...option) {
 	Logger = New(opts...)
 }
 
-// InitFromLoader initalizes default logger
+// InitFromLoader initalizes default logger and registers named loggers of
+// config, see Get
 func InitFromLoader(cfg *loader.Loader, appName string) {
 	Logger = NewFromConfig(cfg, appName)
+	for name, logger := range NewLoggersFromConfig(cfg, appName) {
+		Register(name, logger)
+	}
 }
 
 // New returns a new instance of standard logger
@@ -32,184 +36,197 @@ func New(opts ...option) *Instance {
 }
 
 // NewFromConfig builds a logger from given loader config
-// appName is used in syslog
-func NewFromConfig(cfg *loader.Loader, appName string) (ins *Instance) {
+// appName is used in syslog. Errors of cfg.Validate are printed to stderr, as
+// invalid values are replaced by defaults.
+func NewFromConfig(cfg *loader.Loader, appName string) *Instance {
+	for _, err := range cfg.Validate() {
+		fmt.Fprintf(os.Stderr, "nlog: invalid config: %v\n", err)
+	}
+	return newFromConfig(cfg, appName)
+}
+
+// newFromConfig builds a logger from given loader config without validating it
+func newFromConfig(cfg *loader.Loader, appName string) (ins *Instance) {
 	ins = &Instance{
 		cfg: &config{
-			MinLevel: cfg.Level,
-			Prefix:   cfg.Prefix,
+			MinLevel:   cfg.Level,
+			Prefix:     cfg.Prefix,
+			formatters: formattersFromConfig(cfg, appName),
 		},
 	}
+	ins.SetDefaults()
+	return ins
+}
+
+// formattersFromConfig builds formatters of given loader config
+func formattersFromConfig(cfg *loader.Loader, appName string) []nlog.Formatter {
+	var formatters []nlog.Formatter
 	for _, f := range cfg.Formatters {
-		switch f.Type {
-		case "console":
-			ins.cfg.formatters = append(ins.cfg.formatters, console.NewFromConfig(f, appName))
-		case "json":
-			ins.cfg.formatters = append(ins.cfg.formatters, json.NewFromConfig(f, appName))
-		default:
-			fmt.Fprintf(os.Stderr, "invalid formater type %s\n", f.Type)
+		factory, ok := nlog.LookupFormatter(f.Type)
+		if !ok {
+			continue
+		}
+		formatter, err := factory(f, appName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config for %s formatter, err: %v\n", f.Type, err)
 			continue
 		}
+		formatters = append(formatters, formatter)
 	}
-	ins.SetDefaults()
-	return ins
+	return formatters
 }
 
+// SetDefaults adds default console formatter if there is no formatter and
+// initializes formatters
 func (ins *Instance) SetDefaults() {
-	if len(ins.cfg.formatters) == 0 {
-		ins.cfg.formatters = append(ins.cfg.formatters, console.NewFormatter(
-			console.WithDate(),
-			console.WithTime(),
-		))
-	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Init()
+	if ins.shared == nil {
+		ins.shared = new(shared)
 	}
-	ins.itemPool = pool.NewItemPool(4, nlog.DEBUG, ins.cfg.formatters...)
+	ins.shared.cur.Store(newState(ins.cfg.MinLevel, ins.cfg.Prefix, ins.cfg.formatters))
 }
 
 // Flush flushes to disk and closes writers
 func (ins *Instance) Flush() {
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Flush()
+	s := ins.load()
+	for i := range s.formatters {
+		s.formatters[i].Flush()
 	}
 }
 
 // Fatal returns FATAL level logger item
 func (ins *Instance) Fatal() nlog.LoggerItem {
-	if ins.cfg.MinLevel < nlog.FATAL {
-		return pool.NullItem
-	}
-	return ins.itemPool.Get(nlog.FATAL, ins.cfg.Prefix, 0)
+	return ins.item(nlog.FATAL)
 }
 
 // Fatalf logs FATAL level log with given format-params and exits
 func (ins *Instance) Fatalf(format string, args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.FATAL {
+	s := ins.acquire(nlog.FATAL)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(ins.cfg.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.FATAL, nil, ins.cfg.Prefix, format, args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(s.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.FATAL, nil, ins.prefix(s), format, args...)
 	}
 }
 
 // Error returns ERROR level logger item
 func (ins *Instance) Error() nlog.LoggerItem {
-	if ins.cfg.MinLevel < nlog.ERROR {
-		return pool.NullItem
-	}
-	return ins.itemPool.Get(nlog.ERROR, ins.cfg.Prefix, 0)
+	return ins.item(nlog.ERROR)
 }
 
 // Errorf logs ERROR level log with given format-params
 func (ins *Instance) Errorf(format string, args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.ERROR {
+	s := ins.acquire(nlog.ERROR)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(ins.cfg.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.ERROR, nil, ins.cfg.Prefix, format, args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(s.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.ERROR, nil, ins.prefix(s), format, args...)
 	}
 }
 
 // Warn returns WARNING level logger item
 func (ins *Instance) Warn() nlog.LoggerItem {
-	if ins.cfg.MinLevel < nlog.WARNING {
-		return pool.NullItem
-	}
-	return ins.itemPool.Get(nlog.WARNING, ins.cfg.Prefix, 0)
+	return ins.item(nlog.WARNING)
 }
 
 // Warnf logs WARN level log with given format-params
 func (ins *Instance) Warnf(format string, args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.WARNING {
+	s := ins.acquire(nlog.WARNING)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(ins.cfg.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.WARNING, nil, ins.cfg.Prefix, format, args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(s.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.WARNING, nil, ins.prefix(s), format, args...)
 	}
 }
 
 // Info logs info message if logging level is satisfied
 func (ins *Instance) Info() nlog.LoggerItem {
-	if ins.cfg.MinLevel < nlog.INFO {
-		return pool.NullItem
-	}
-	return ins.itemPool.Get(nlog.INFO, ins.cfg.Prefix, 0)
+	return ins.item(nlog.INFO)
 }
 
 // Infof logs info message with format if logging level is satisfied
 func (ins *Instance) Infof(format string, args ...interface{}) {
-
-	if ins.cfg.MinLevel < nlog.INFO {
+	s := ins.acquire(nlog.INFO)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(ins.cfg.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.INFO, nil, ins.cfg.Prefix, format, args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(s.formatters[i].GetCallDepth(ins.cfg.SubDepth), nlog.INFO, nil, ins.prefix(s), format, args...)
 	}
 }
 
 // Debug returns DEBUG level logger item
 func (ins *Instance) Debug() nlog.LoggerItem {
-	if ins.cfg.MinLevel < nlog.DEBUG {
-		return pool.NullItem
-	}
-	return ins.itemPool.Get(nlog.DEBUG, ins.cfg.Prefix, 0)
+	return ins.item(nlog.DEBUG)
 }
 
 // Debugf prints DEBUG level message with given format-params
 func (ins *Instance) Debugf(format string, args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.DEBUG {
+	s := ins.acquire(nlog.DEBUG)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(0, nlog.DEBUG, nil, ins.cfg.Prefix, format, args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(0, nlog.DEBUG, nil, ins.prefix(s), format, args...)
 	}
 }
 
 // Print prints log at INFO level with given params
 func (ins *Instance) Print(args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.DEBUG {
+	s := ins.acquire(nlog.DEBUG)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(0, nlog.DEBUG, nil, ins.cfg.Prefix, "", args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(0, nlog.DEBUG, nil, ins.prefix(s), "", args...)
 	}
 }
 
 // Printf prints log at INFO level with given format-params
 func (ins *Instance) Printf(format string, args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.DEBUG {
+	s := ins.acquire(nlog.DEBUG)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(0, nlog.DEBUG, nil, ins.cfg.Prefix, format, args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(0, nlog.DEBUG, nil, ins.prefix(s), format, args...)
 	}
 }
 
 // Print prints log at INFO level with given params
 func (ins *Instance) Println(args ...interface{}) {
-	if ins.cfg.MinLevel < nlog.DEBUG {
+	s := ins.acquire(nlog.DEBUG)
+	if s == nil {
 		return
 	}
-	for i := range ins.cfg.formatters {
-		ins.cfg.formatters[i].Logf(0, nlog.DEBUG, nil, ins.cfg.Prefix, "", args...)
+	defer s.release()
+	for i := range s.formatters {
+		s.formatters[i].Logf(0, nlog.DEBUG, nil, ins.prefix(s), "", args...)
 	}
 }
 
 // Sub returns a sub logger with given prefix
 func (ins *Instance) Sub(prefix string) interface{} {
 	return &Instance{
-		itemPool: ins.itemPool,
+		shared: ins.shared,
+		sub:    true,
 		cfg: &config{
-			Prefix:     prefix,
-			MinLevel:   ins.cfg.MinLevel,
-			formatters: ins.cfg.formatters,
-			SubDepth:   1,
+			Prefix:   prefix,
+			SubDepth: 1,
 		},
 	}
 }
 
 // GetLevel returns logger level
 func (ins *Instance) GetLevel() int {
-	return int(ins.cfg.MinLevel)
+	return int(ins.load().MinLevel)
 }
@@ -2,10 +2,15 @@ package log
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/derkan/nlog"
 	"github.com/derkan/nlog/formatter/console"
+	"github.com/derkan/nlog/formatter/json"
+	"github.com/derkan/nlog/loader"
 )
 
 type BuffCloser struct {
@@ -109,3 +114,72 @@ func BenchmarkZeroInfof(b *testing.B) {
 	}
 }
 */
+
+func TestRegisteredConfig(t *testing.T) {
+	output := &BuffCloser{&bytes.Buffer{}}
+	var prefix string
+	nlog.RegisterWriter("nlogtest.buffer", func(opts map[string]string, appName string) (io.WriteCloser, error) {
+		prefix = opts["prefix"] + appName
+		return output, nil
+	})
+	nlog.RegisterHook("nlogtest.host", nlog.HookFunc(func(level nlog.Level, buffer nlog.HookBufferSet, message string) {
+		buffer.With("host", "web1")
+	}))
+	cfg, err := loader.FromContent(`
+log:
+  level: INFO
+  formatters:
+    - type: json
+      hooks: [nlogtest.host]
+      writers:
+        - type: nlogtest.buffer
+          options:
+            prefix: app-
+`, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	logger.Infof("registered")
+	logger.Flush()
+	if prefix != "app-nlogtest" {
+		t.Fatalf("expected writer options and app name passed to factory, got %q", prefix)
+	}
+	if line := output.String(); !strings.Contains(line, `"host":"web1"`) || !strings.Contains(line, "registered") {
+		t.Fatalf("expected line with hook field written to registered writer, got %q", line)
+	}
+}
+
+func TestRegisteredFormatter(t *testing.T) {
+	output := &BuffCloser{&bytes.Buffer{}}
+	nlog.RegisterWriter("nlogtest.formatter", func(opts map[string]string, appName string) (io.WriteCloser, error) {
+		return output, nil
+	})
+	nlog.RegisterFormatter("nlogtest.keyed", func(cfg interface{}, appName string) (nlog.Formatter, error) {
+		f := cfg.(loader.Formatter)
+		if f.Options["key"] == "" {
+			return nil, errors.New("key option is required")
+		}
+		json.MsgKey = f.Options["key"]
+		return json.NewFromConfig(f, appName), nil
+	})
+	defer func() { json.MsgKey = "msg" }()
+	cfg, err := loader.FromContent(`
+log:
+  formatters:
+    - type: nlogtest.keyed
+      options:
+        key: message
+      writers:
+        - type: nlogtest.formatter
+`, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	logger.Infof("registered")
+	logger.Flush()
+	if line := output.String(); !strings.Contains(line, `"message":"registered"`) {
+		t.Fatalf("expected line written by registered formatter, got %q", line)
+	}
+}
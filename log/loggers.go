@@ -0,0 +1,80 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+)
+
+// loggers are named loggers, see Get
+var loggers = struct {
+	sync.RWMutex
+	m map[string]nlog.Logger
+}{m: make(map[string]nlog.Logger)}
+
+// Register makes logger available by name with Get. It replaces any logger
+// registered with the same name.
+func Register(name string, logger nlog.Logger) {
+	loggers.Lock()
+	defer loggers.Unlock()
+	loggers.m[name] = logger
+}
+
+// Get returns logger registered with given name by Register or
+// InitFromLoader, default Logger if there is none.
+func Get(name string) nlog.Logger {
+	loggers.RLock()
+	defer loggers.RUnlock()
+	if logger, ok := loggers.m[name]; ok {
+		return logger
+	}
+	return Logger
+}
+
+// NewLoggersFromConfig builds named loggers in loggers key of given loader
+// config by name. Writers referring to same shared writer share it, like a
+// rotated file, which is closed when all loggers using it are flushed.
+// Errors of named loggers are printed by NewFromConfig of cfg.
+func NewLoggersFromConfig(cfg *loader.Loader, appName string) map[string]*Instance {
+	named := make(map[string]*Instance, len(cfg.Loggers))
+	for name, l := range cfg.Loggers {
+		named[name] = newFromConfig(l, appName)
+	}
+	return named
+}
+
+// ReloadLoggers reloads registered named loggers with their configs in
+// loggers key of cfg, like Instance.Reload. Loggers added to cfg are built and
+// registered, loggers removed from it and loggers which are not an Instance
+// are not changed. If cfg is not valid, no logger is changed and errors of
+// cfg.Validate are returned.
+func ReloadLoggers(cfg *loader.Loader, appName string) error {
+	if errs := cfg.Validate(); errs != nil {
+		return errs
+	}
+	for name, l := range cfg.Loggers {
+		loggers.RLock()
+		logger, ok := loggers.m[name]
+		loggers.RUnlock()
+		if !ok {
+			Register(name, newFromConfig(l, appName))
+			continue
+		}
+		if ins, ok := logger.(*Instance); ok {
+			if err := ins.Reload(l, appName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flushLoggers flushes registered loggers
+func flushLoggers() {
+	loggers.RLock()
+	defer loggers.RUnlock()
+	for _, logger := range loggers.m {
+		logger.Flush()
+	}
+}
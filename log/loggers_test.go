@@ -0,0 +1,126 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nlogloggers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+	cfg, err := loader.FromContent(`
+log:
+  level: ERROR
+  writers:
+    app:
+      type: filerotator
+      filename: `+filename+`
+  formatters:
+    - type: json
+      writers:
+        - ref: app
+  loggers:
+    http:
+      level: INFO
+      formatters:
+        - type: json
+          writers:
+            - ref: app
+    audit:
+      level: INFO
+      formatters:
+        - type: json
+          writers:
+            - ref: app
+              levels: [WARNING]
+`, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(logger nlog.Logger) { Logger = logger }(Logger)
+	InitFromLoader(cfg, "nlogtest")
+	defer func() {
+		loggers.Lock()
+		delete(loggers.m, "http")
+		delete(loggers.m, "audit")
+		loggers.Unlock()
+	}()
+
+	if Get("missing") != Logger {
+		t.Fatal("expected default logger for unknown name")
+	}
+	if Get("http").GetLevel() != int(nlog.INFO) || Get("audit") == Get("http") {
+		t.Fatal("expected registered named loggers")
+	}
+	Infof("skipped")
+	Errorf("root")
+	Get("http").Infof("http")
+	Get("audit").Infof("skipped")
+	Get("audit").Warnf("audit")
+	Flush()
+	if n := countLines(t, filename); n != 3 {
+		t.Fatalf("expected 3 lines written to shared file, got %d", n)
+	}
+}
+
+func TestReloadLoggers(t *testing.T) {
+	config := func(level string) string {
+		return `
+log:
+  loggers:
+    http:
+      level: ` + level + `
+      formatters:
+        - writers:
+            - type: stderr
+`
+	}
+	cfg, err := loader.FromContent(config("INFO"), "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(logger nlog.Logger) { Logger = logger }(Logger)
+	InitFromLoader(cfg, "nlogtest")
+	defer func() {
+		loggers.Lock()
+		delete(loggers.m, "http")
+		delete(loggers.m, "db")
+		loggers.Unlock()
+	}()
+	http := Get("http")
+
+	cfg, err = loader.FromContent(config("ERROR")+`
+    db:
+      level: WARNING
+`, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReloadLoggers(cfg, "nlogtest"); err != nil {
+		t.Fatal(err)
+	}
+	if Get("http") != http || http.GetLevel() != int(nlog.ERROR) {
+		t.Fatalf("expected registered logger reloaded, got level %d", http.GetLevel())
+	}
+	if Get("db") == Logger || Get("db").GetLevel() != int(nlog.WARNING) {
+		t.Fatal("expected logger added to config registered")
+	}
+
+	cfg, err = loader.FromContent(config("VERBOSE"), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReloadLoggers(cfg, "nlogtest"); err == nil || http.GetLevel() != int(nlog.ERROR) {
+		t.Fatalf("expected invalid config rejected, got %v", err)
+	}
+	Flush()
+}
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/formatter/console"
+	"github.com/derkan/nlog/loader"
+	"github.com/derkan/nlog/pool"
+)
+
+// drainTimeout is maximum time Reload waits for lines being written with
+// previous formatters before closing their writers. Items which are never
+// written with Msg/Msgf are waited for until it passes.
+const drainTimeout = 5 * time.Second
+
+// state holds level, prefix and formatters of a logger
+type state struct {
+	// inflight counts calls writing with formatters, see acquire
+	inflight   int64
+	MinLevel   nlog.Level
+	Prefix     string
+	formatters []nlog.Formatter
+	itemPool   pool.ItemPool
+}
+
+// newState returns state for formatters, adding default console formatter if
+// there is no formatter
+func newState(minLevel nlog.Level, prefix string, formatters []nlog.Formatter) *state {
+	if len(formatters) == 0 {
+		formatters = append(formatters, console.NewFormatter(
+			console.WithDate(),
+			console.WithTime(),
+		))
+	}
+	for i := range formatters {
+		formatters[i].Init()
+	}
+	s := &state{MinLevel: minLevel, Prefix: prefix, formatters: formatters}
+	s.itemPool = pool.NewItemPool(4, nlog.DEBUG, formatters...).WithRelease(s.release)
+	return s
+}
+
+// release ends an in-flight call started by acquire
+func (s *state) release() {
+	atomic.AddInt64(&s.inflight, -1)
+}
+
+// drain waits until in-flight calls complete or timeout passes
+func (s *state) drain(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&s.inflight) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// shared holds current state of a logger and its sub loggers
+type shared struct {
+	// mu serializes reloads
+	mu  sync.Mutex
+	cur atomic.Value
+}
+
+// load returns current state
+func (ins *Instance) load() *state {
+	return ins.shared.cur.Load().(*state)
+}
+
+// acquire returns current state if lvl is enabled, nil otherwise. The call
+// is in-flight until release of returned state is called, so Reload does not
+// close its writers before lines are written.
+func (ins *Instance) acquire(lvl nlog.Level) *state {
+	for {
+		s := ins.load()
+		if s.MinLevel < lvl {
+			return nil
+		}
+		atomic.AddInt64(&s.inflight, 1)
+		// state may be replaced and drained before it was counted
+		if ins.load() == s {
+			return s
+		}
+		s.release()
+	}
+}
+
+// item returns logger item of lvl, released when its line is written
+func (ins *Instance) item(lvl nlog.Level) nlog.LoggerItem {
+	s := ins.acquire(lvl)
+	if s == nil {
+		return pool.NullItem
+	}
+	return s.itemPool.Get(lvl, ins.prefix(s), 0)
+}
+
+// prefix returns prefix of lines, sub loggers keep their own prefix
+func (ins *Instance) prefix(s *state) string {
+	if ins.sub {
+		return ins.cfg.Prefix
+	}
+	return s.Prefix
+}
+
+// Reload replaces level, prefix and formatters of logger and its sub loggers
+// with ones built from cfg. Lines being written while reloading are written
+// with previous formatters, whose writers are closed after that. If cfg is
+// not valid, logger is not changed and errors of cfg.Validate are returned.
+func (ins *Instance) Reload(cfg *loader.Loader, appName string) error {
+	if errs := cfg.Validate(); errs != nil {
+		return errs
+	}
+	ins.shared.mu.Lock()
+	defer ins.shared.mu.Unlock()
+	old := ins.load()
+	ins.shared.cur.Store(newState(cfg.Level, cfg.Prefix, formattersFromConfig(cfg, appName)))
+	old.drain(drainTimeout)
+	for i := range old.formatters {
+		old.formatters[i].Flush()
+	}
+	return nil
+}
+
+// WatchConfig reloads logger from config file filename when it changes,
+// checking it every interval. Config is loaded by loader.FromFile with opts in
+// strict mode, so logger keeps previous config if new one is not valid. opts
+// are ones used for first load, like loader.WithEnvPrefix. Errors are printed
+// to stderr. Watching stops when returned watcher is closed.
+//
+// Only this logger is reloaded, named loggers of config are not. Use
+// WatchConfig function for loggers initialized by InitFromLoader.
+func (ins *Instance) WatchConfig(filename, baseKey, appName string, interval time.Duration, opts ...loader.Option) *loader.Watcher {
+	return watchConfig(filename, baseKey, interval, opts, func(cfg *loader.Loader) error {
+		return ins.Reload(cfg, appName)
+	})
+}
+
+// WatchConfig reloads default logger and named loggers initialized by
+// InitFromLoader from config file filename when it changes, like
+// Instance.WatchConfig. See ReloadLoggers for named loggers.
+func WatchConfig(filename, baseKey, appName string, interval time.Duration, opts ...loader.Option) *loader.Watcher {
+	return watchConfig(filename, baseKey, interval, opts, func(cfg *loader.Loader) error {
+		if ins, ok := Logger.(*Instance); ok {
+			if err := ins.Reload(cfg, appName); err != nil {
+				return err
+			}
+		}
+		return ReloadLoggers(cfg, appName)
+	})
+}
+
+// watchConfig calls reload with config loaded from filename with opts in
+// strict mode when it changes
+func watchConfig(filename, baseKey string, interval time.Duration, opts []loader.Option, reload func(cfg *loader.Loader) error) *loader.Watcher {
+	opts = append(append([]loader.Option(nil), opts...), loader.WithStrict())
+	return loader.Watch(filename, interval, func() {
+		cfg, err := loader.FromFile(filename, baseKey, opts...)
+		if err == nil {
+			err = reload(cfg)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nlog: keeping previous config, reloading %s failed: %v\n", filename, err)
+		}
+	})
+}
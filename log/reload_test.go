@@ -0,0 +1,214 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/loader"
+)
+
+// fileConfig returns config writing lines of level to filename
+func fileConfig(level, filename string) string {
+	return fmt.Sprintf(`
+log:
+  level: %s
+  formatters:
+    - type: json
+      writers:
+        - type: filerotator
+          filename: %s
+`, level, filename)
+}
+
+func countLines(t *testing.T, filename string) int {
+	t.Helper()
+	b, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return bytes.Count(b, []byte{'\n'})
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nlogreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+
+	cfg, err := loader.FromContent(fileConfig("INFO", first), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	sub := logger.Sub("db").(*Instance)
+
+	var wg sync.WaitGroup
+	const goroutines, lines = 4, 200
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				if i%2 == 0 {
+					logger.Info().Int("i", i).Msg("line")
+				} else {
+					sub.Infof("line %d", i)
+				}
+			}
+		}()
+	}
+	cfg, err = loader.FromContent(fileConfig("DEBUG", second), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Reload(cfg, "nlogtest"); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if sub.GetLevel() != int(nlog.DEBUG) {
+		t.Fatalf("expected sub logger level reloaded, got %d", sub.GetLevel())
+	}
+	sub.Debugf("after reload")
+	logger.Flush()
+	if n := countLines(t, first) + countLines(t, second); n != goroutines*lines+1 {
+		t.Fatalf("expected %d lines written, got %d", goroutines*lines+1, n)
+	}
+
+	cfg, err = loader.FromContent(fileConfig("VERBOSE", first), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Reload(cfg, "nlogtest"); err == nil {
+		t.Fatal("expected invalid config to be rejected")
+	}
+	if logger.GetLevel() != int(nlog.DEBUG) {
+		t.Fatalf("expected previous level kept, got %d", logger.GetLevel())
+	}
+}
+
+func TestReloadStd(t *testing.T) {
+	content := `
+log:
+  formatters:
+    - type: console
+      writers:
+        - type: stderr
+          level: ERROR
+    - type: json
+`
+	cfg, err := loader.FromContent(content, "log", loader.WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	if err := logger.Reload(cfg, "nlogtest"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Flush()
+	if _, err := fmt.Fprint(os.Stderr, ""); err != nil {
+		t.Fatalf("expected stderr open after reload, got %v", err)
+	}
+	if _, err := os.Stderr.Stat(); err != nil {
+		t.Fatalf("expected stderr open after reload, got %v", err)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nlogwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "log.yaml")
+	logFile := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(filename, []byte(fileConfig("ERROR", logFile)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loader.FromFile(filename, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	defer logger.Flush()
+	w := logger.WatchConfig(filename, "log", "nlogtest", 5*time.Millisecond)
+	defer w.Close()
+
+	// rename a new file on config file, as editors do
+	waitLevel := func(content string, want nlog.Level) {
+		t.Helper()
+		tmp := filename + ".tmp"
+		if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, filename); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for logger.GetLevel() != int(want) && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if logger.GetLevel() != int(want) {
+			t.Fatalf("expected level %d after config change, got %d", want, logger.GetLevel())
+		}
+	}
+	waitLevel(fileConfig("INFO", logFile), nlog.INFO)
+	logger.Infof("reloaded")
+	waitLevel("log:\n  level: [broken\n", nlog.INFO)
+	waitLevel(fileConfig("WARNING", logFile), nlog.WARNING)
+	if countLines(t, logFile) != 1 {
+		t.Fatalf("expected line written after reload, got %d lines", countLines(t, logFile))
+	}
+}
+
+func TestWatchConfigOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nlogwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "log.yaml")
+	content := func(prefix string) []byte {
+		return []byte("log:\n  level: ERROR\n  prefix: " + prefix + "\n  formatters:\n    - type: json\n")
+	}
+	if err := ioutil.WriteFile(filename, content("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("NLOGWATCH_LEVEL", "WARNING")
+	defer os.Unsetenv("NLOGWATCH_LEVEL")
+	opt := loader.WithEnvPrefix("NLOGWATCH_")
+	cfg, err := loader.FromFile(filename, "log", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := NewFromConfig(cfg, "nlogtest")
+	defer logger.Flush()
+	w := logger.WatchConfig(filename, "log", "nlogtest", 5*time.Millisecond, opt)
+	defer w.Close()
+
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, content("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for logger.load().Prefix != "second" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if logger.load().Prefix != "second" {
+		t.Fatal("expected config reloaded")
+	}
+	if logger.GetLevel() != int(nlog.WARNING) {
+		t.Fatalf("expected level overridden on reload, got %d", logger.GetLevel())
+	}
+}
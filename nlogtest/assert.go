@@ -0,0 +1,46 @@
+package nlogtest
+
+import (
+	"testing"
+
+	"github.com/derkan/nlog"
+)
+
+// AssertLogged fails t unless rec has an entry of level with message msg
+// having given fields. Values of fields are compared with their fmt.Sprint
+// formatting, only given fields are compared.
+func AssertLogged(t testing.TB, rec *Recorder, level nlog.Level, msg string, fields Fields) bool {
+	t.Helper()
+	if len(rec.Find(level, msg, fields)) > 0 {
+		return true
+	}
+	t.Errorf("expected %s line %q with fields %v, logged:\n%s", nlog.LevelNames[level], msg, fields, rec)
+	return false
+}
+
+// AssertNotLogged fails t if rec has an entry of level with message msg
+// having given fields.
+func AssertNotLogged(t testing.TB, rec *Recorder, level nlog.Level, msg string, fields Fields) bool {
+	t.Helper()
+	if found := rec.Find(level, msg, fields); len(found) > 0 {
+		t.Errorf("expected no %s line %q with fields %v, logged:\n%s", nlog.LevelNames[level], msg, fields, found[0])
+		return false
+	}
+	return true
+}
+
+// AssertCount fails t unless rec has n entries of level.
+func AssertCount(t testing.TB, rec *Recorder, level nlog.Level, n int) bool {
+	t.Helper()
+	var got int
+	for _, e := range rec.Entries() {
+		if e.Level == level {
+			got++
+		}
+	}
+	if got != n {
+		t.Errorf("expected %d %s lines, got %d, logged:\n%s", n, nlog.LevelNames[level], got, rec)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package nlogtest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/formatter/console"
+	"github.com/derkan/nlog/formatter/json"
+	"github.com/derkan/nlog/log"
+)
+
+// Fields are expected fields of a log line
+type Fields map[string]interface{}
+
+// config is for test logger settings
+type config struct {
+	// JSON selects json formatter instead of console formatter
+	JSON bool
+	// Level is most verbose level logged
+	Level nlog.Level
+	// Prefix is name of logger
+	Prefix string
+}
+
+// option is function type used for setting test logger config attributes
+type option func(*config)
+
+// WithJSON makes test logger use json formatter. Console formatter is used
+// by default.
+func WithJSON() option {
+	return func(c *config) {
+		c.JSON = true
+	}
+}
+
+// WithLevel sets most verbose level logged. It defaults to nlog.DEBUG.
+func WithLevel(level nlog.Level) option {
+	return func(c *config) {
+		c.Level = level
+	}
+}
+
+// WithPrefix sets logger name of test logger
+func WithPrefix(prefix string) option {
+	return func(c *config) {
+		c.Prefix = prefix
+	}
+}
+
+// New returns a logger writing to a new Recorder. Logger is flushed when test
+// and its subtests complete.
+func New(t testing.TB, opts ...option) (*log.Instance, *Recorder) {
+	t.Helper()
+	rec := NewRecorder()
+	return newLogger(t, rec, opts...), rec
+}
+
+// newLogger returns a logger writing to w, flushed when test completes
+func newLogger(t testing.TB, w io.WriteCloser, opts ...option) *log.Instance {
+	c := &config{Level: nlog.DEBUG}
+	for _, opt := range opts {
+		opt(c)
+	}
+	var f nlog.Formatter
+	if c.JSON {
+		f = json.NewFormatter(json.WithLevel(c.Level), json.WithWriter(w, c.Level))
+	} else {
+		f = console.NewFormatter(console.WithLevel(c.Level), console.WithWriter(w, c.Level))
+	}
+	ins := log.New(log.WithMinLevel(c.Level), log.WithPrefix(c.Prefix), log.WithFormatter(f))
+	t.Cleanup(ins.Flush)
+	return ins
+}
@@ -0,0 +1,129 @@
+package nlogtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/log"
+)
+
+func TestConsole(t *testing.T) {
+	logger, rec := New(t)
+	logger.Info().Str("user", "joe").Msg("logged in")
+	logger.Info().Int("id", 7).Msg("query")
+	logger.Errorf("query failed: %s", "timeout")
+	logger.Debug().Bool("cached", true).Msg("lookup")
+
+	AssertLogged(t, rec, nlog.INFO, "logged in", Fields{"user": "joe"})
+	AssertLogged(t, rec, nlog.INFO, "query", Fields{"id": 7})
+	AssertLogged(t, rec, nlog.ERROR, "query failed: timeout", nil)
+	AssertLogged(t, rec, nlog.DEBUG, "lookup", Fields{"cached": true})
+	AssertNotLogged(t, rec, nlog.INFO, "query", Fields{"id": 8})
+	AssertCount(t, rec, nlog.INFO, 2)
+}
+
+func TestJSON(t *testing.T) {
+	logger, rec := New(t, WithJSON(), WithLevel(nlog.INFO))
+	logger.Info().Str("user", "joe").Msg("logged in")
+	logger.Info().Float64("ratio", 0.5).Msg("ratio")
+	logger.Debug().Msg("skipped")
+	sub := logger.Sub("db").(*log.Instance)
+	sub.Warn().Int("took", 3).Msg("slow query")
+
+	AssertLogged(t, rec, nlog.INFO, "logged in", Fields{"user": "joe"})
+	AssertLogged(t, rec, nlog.INFO, "ratio", Fields{"ratio": 0.5})
+	AssertNotLogged(t, rec, nlog.DEBUG, "skipped", nil)
+	AssertLogged(t, rec, nlog.WARNING, "slow query", Fields{"took": 3})
+	entries := rec.Entries()
+	if len(entries) != 3 || entries[2].Logger != "db" {
+		t.Fatalf("expected 3 entries with sub logger name db, got %v", entries)
+	}
+}
+
+func TestAssertFails(t *testing.T) {
+	logger, rec := New(t)
+	logger.Infof("started")
+	ft := &fakeT{TB: t}
+	if AssertLogged(ft, rec, nlog.INFO, "stopped", nil) || !ft.failed {
+		t.Fatal("expected AssertLogged to fail")
+	}
+	ft = &fakeT{TB: t}
+	if AssertNotLogged(ft, rec, nlog.INFO, "started", nil) || !ft.failed {
+		t.Fatal("expected AssertNotLogged to fail")
+	}
+	ft = &fakeT{TB: t}
+	if AssertCount(ft, rec, nlog.INFO, 2) || !ft.failed {
+		t.Fatal("expected AssertCount to fail")
+	}
+}
+
+func TestRecorderClosedOnCleanup(t *testing.T) {
+	var rec *Recorder
+	t.Run("sub", func(t *testing.T) {
+		_, rec = New(t)
+	})
+	if !rec.Closed() {
+		t.Fatal("expected recorder closed after test")
+	}
+}
+
+// fakeT records failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed   bool
+	msg      string
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failed = true
+	f.msg = fmt.Sprintf(format, args...)
+}
+
+func TestTWriter(t *testing.T) {
+	ft := &fakeT{TB: t}
+	logger := NewT(ft)
+	logger.Info().Int("id", 7).Msg("started")
+	if len(ft.logs) != 1 || !strings.HasPrefix(ft.logs[0], "nlogtest_test.go:") ||
+		!strings.HasSuffix(ft.logs[0], "INF started  id=7") {
+		t.Fatalf("expected line sent to t.Log, got %q", ft.logs)
+	}
+	ft.cleanup()
+	logger.Info().Msg("after test")
+	if len(ft.logs) != 1 {
+		t.Fatalf("expected line dropped after test completed, got %q", ft.logs)
+	}
+}
+
+func TestTWriterParallel(t *testing.T) {
+	for _, name := range []string{"a", "b"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			logger := NewT(t, WithPrefix(name))
+			logger.Debugf("running %s", name)
+			logger.Info().Str("test", name).Msg("running")
+		})
+	}
+}
+
+func (f *fakeT) Log(args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// cleanup runs registered cleanup functions in reverse order, as testing does.
+func (f *fakeT) cleanup() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+	f.cleanups = nil
+}
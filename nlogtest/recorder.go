@@ -0,0 +1,165 @@
+// Package nlogtest provides an in-memory writer capturing log lines and
+// helpers asserting logged lines in tests.
+package nlogtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/writer"
+)
+
+// ensure we always implement writer.LeveledWriter
+var _ writer.LeveledWriter = (*Recorder)(nil)
+
+// Entry is a captured log line
+type Entry struct {
+	Level nlog.Level
+	// Logger is sub logger name
+	Logger  string
+	Message string
+	// Fields holds fields of line with values as written, strings unquoted
+	Fields map[string]string
+	// Line is the line as written by formatter
+	Line string
+}
+
+// String returns line of entry
+func (e Entry) String() string {
+	return e.Line
+}
+
+// Recorder is a writer.LeveledWriter keeping written lines in memory as
+// entries. Lines written by json and console formatters are parsed.
+//
+// It is concurrent safe.
+type Recorder struct {
+	mu      sync.Mutex
+	level   nlog.Level
+	entries []Entry
+	closed  bool
+}
+
+// NewRecorder returns a new Recorder capturing lines of all levels.
+func NewRecorder() *Recorder {
+	return &Recorder{level: nlog.DEBUG}
+}
+
+// Write implements io.Writer. Level is read from the line, INFO is used if it
+// can't be found.
+func (r *Recorder) Write(p []byte) (n int, err error) {
+	lvl := nlog.INFO
+	if e, ok := writer.ParseLine(p); ok {
+		if l, ok := writer.LevelFromString(e.Level); ok {
+			lvl = l
+		}
+	}
+	return r.WriteIfLevel(lvl, p)
+}
+
+// WriteIfLevel records p as an entry of lvl.
+func (r *Recorder) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	line := string(p)
+	entry := Entry{Level: lvl, Line: strings.TrimRight(line, "\n")}
+	if e, ok := writer.ParseLine(p); ok {
+		entry.Logger = e.Logger
+		entry.Message = e.Message
+		entry.Fields = make(map[string]string, len(e.Fields))
+		for _, f := range e.Fields {
+			entry.Fields[f.Key] = f.Value
+		}
+	} else {
+		entry.Message = entry.Line
+	}
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+// GetLevel returns nlog.DEBUG, all levels are recorded
+func (r *Recorder) GetLevel() nlog.Level {
+	return r.level
+}
+
+// Enabled returns true, all levels are recorded
+func (r *Recorder) Enabled(lvl nlog.Level) bool {
+	return true
+}
+
+// Sync does nothing
+func (r *Recorder) Sync() error {
+	return nil
+}
+
+// Close marks recorder as closed, entries are kept
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	return nil
+}
+
+// Closed returns true if Close was called
+func (r *Recorder) Closed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// Entries returns a copy of recorded entries
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Len returns number of recorded entries
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Reset forgets recorded entries
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// String returns recorded lines joined with new lines
+func (r *Recorder) String() string {
+	var sb strings.Builder
+	for _, e := range r.Entries() {
+		sb.WriteString(e.Line)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Find returns entries of level with message msg having given fields.
+// Values of fields are compared with their fmt.Sprint formatting, only given
+// fields are compared.
+func (r *Recorder) Find(level nlog.Level, msg string, fields Fields) []Entry {
+	var found []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level && e.Message == msg && e.has(fields) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// has returns true if entry has given fields.
+func (e Entry) has(fields Fields) bool {
+	for k, v := range fields {
+		got, ok := e.Fields[k]
+		if !ok || got != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package nlogtest
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/log"
+	"github.com/derkan/nlog/writer"
+)
+
+// ensure we always implement writer.LeveledWriter
+var _ writer.LeveledWriter = (*TWriter)(nil)
+
+// nlogPkg is import path prefix of nlog packages skipped finding caller
+const nlogPkg = "github.com/derkan/nlog"
+
+// TWriter is a writer.LeveledWriter sending each line to t.Log, so output of
+// parallel tests is grouped per test by `go test -v`.
+//
+// As t.Log reports location in nlog writer, lines are prefixed with file:line
+// of logging call. Lines written after test completes are dropped instead of
+// panicking.
+type TWriter struct {
+	mu   sync.Mutex
+	t    testing.TB
+	done bool
+}
+
+// NewTWriter returns a TWriter logging to t until t and its subtests complete.
+func NewTWriter(t testing.TB) *TWriter {
+	w := &TWriter{t: t}
+	t.Cleanup(w.stop)
+	return w
+}
+
+// stop makes writer drop further lines
+func (w *TWriter) stop() {
+	w.mu.Lock()
+	w.done = true
+	w.mu.Unlock()
+}
+
+// Write sends p to t.Log without trailing new line
+func (w *TWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return len(p), nil
+	}
+	w.t.Helper()
+	line := string(bytes.TrimRight(p, "\n"))
+	if loc := callerLoc(); loc != "" {
+		line = loc + ": " + line
+	}
+	w.t.Log(line)
+	return len(p), nil
+}
+
+// callerLoc returns file:line of logging call, first frame out of nlog
+// packages or in a test file. Empty string is returned if it is not in stack,
+// like for parallel writers.
+func callerLoc() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, nlogPkg) || strings.HasSuffix(frame.File, "_test.go") {
+			if strings.HasPrefix(frame.Function, "testing.") || strings.HasPrefix(frame.Function, "runtime.") {
+				return ""
+			}
+			return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// WriteIfLevel sends p to t.Log, lvl is ignored
+func (w *TWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	w.t.Helper()
+	return w.Write(p)
+}
+
+// GetLevel returns nlog.DEBUG, all levels are written
+func (w *TWriter) GetLevel() nlog.Level {
+	return nlog.DEBUG
+}
+
+// Enabled returns true, all levels are written
+func (w *TWriter) Enabled(lvl nlog.Level) bool {
+	return true
+}
+
+// Sync does nothing
+func (w *TWriter) Sync() error {
+	return nil
+}
+
+// Close does nothing, writer stops when test completes
+func (w *TWriter) Close() error {
+	return nil
+}
+
+// NewT returns a logger writing to t.Log, see TWriter. Options of New apply.
+func NewT(t testing.TB, opts ...option) *log.Instance {
+	t.Helper()
+	return newLogger(t, NewTWriter(t), opts...)
+}
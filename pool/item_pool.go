@@ -9,6 +9,8 @@ import (
 // A ItemPool is a type-safe wrapper around a sync.Pool.
 type ItemPool struct {
 	p *sync.Pool
+	// release is called when an item is put back after writing its line
+	release func()
 }
 
 // NewItemPool constructs a new ItemPool.
@@ -20,6 +22,13 @@ func NewItemPool(callDepth int, level nlog.Level, formatters ...nlog.Formatter)
 	}}
 }
 
+// WithRelease returns a copy of pool calling fn when an item got from it is
+// put back after writing its line
+func (p ItemPool) WithRelease(fn func()) ItemPool {
+	p.release = fn
+	return p
+}
+
 // Get retrieves a Buffer from the pool
 func (p ItemPool) Get(lvl nlog.Level, prefix string, subDepth int) *Item {
 	item := p.p.Get().(*Item)
@@ -35,4 +44,7 @@ func (p ItemPool) put(item *Item) {
 		defer PutBuffer(item.buffs[i])
 	}
 	p.p.Put(item)
+	if p.release != nil {
+		p.release()
+	}
 }
@@ -0,0 +1,116 @@
+package nlog
+
+import (
+	"io"
+	"sync"
+)
+
+// FormatterFactory builds a formatter configured in config files. cfg is the
+// loader.Formatter config of formatter, passed as interface{} as loader
+// imports nlog. Its writers can be built with formatter.NewMultiWriter and
+// values of keys under options key are in its Options. appName is name of
+// application given to logger.
+type FormatterFactory func(cfg interface{}, appName string) (Formatter, error)
+
+// WriterFactory builds a custom writer configured in config files. opts are
+// values of keys under options key of writer config, like brokers and topic of
+// a kafka writer. appName is name of application given to logger.
+type WriterFactory func(opts map[string]string, appName string) (io.WriteCloser, error)
+
+// registry holds values registered by name, by kind of value
+var registry = struct {
+	sync.RWMutex
+	m map[string]map[string]interface{}
+}{m: make(map[string]map[string]interface{})}
+
+func register(kind, name string, v interface{}) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.m[kind] == nil {
+		registry.m[kind] = make(map[string]interface{})
+	}
+	registry.m[kind][name] = v
+}
+
+func lookup(kind, name string) (interface{}, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	v, ok := registry.m[kind][name]
+	return v, ok
+}
+
+// RegisterHook makes hook available to config files by name, in hooks of
+// formatters. It replaces any hook registered with the same name.
+func RegisterHook(name string, h Hook) {
+	register("hook", name, h)
+}
+
+// LookupHook returns hook registered with given name.
+func LookupHook(name string) (Hook, bool) {
+	v, ok := lookup("hook", name)
+	if !ok {
+		return nil, false
+	}
+	return v.(Hook), true
+}
+
+// RegisterMarshallFn makes fn available to config files by name, in
+// marshall_fn of formatters. It replaces any fn registered with the same name.
+func RegisterMarshallFn(name string, fn MarshallFn) {
+	register("marshallfn", name, fn)
+}
+
+// LookupMarshallFn returns MarshallFn registered with given name.
+func LookupMarshallFn(name string) (MarshallFn, bool) {
+	v, ok := lookup("marshallfn", name)
+	if !ok {
+		return nil, false
+	}
+	return v.(MarshallFn), true
+}
+
+// RegisterFunc makes callback fn available to config files by name, like
+// post_rotate, on_compressed and error_handler of filerotator writers, which
+// take func(path string), func(src, dst string) and func(err error). It
+// replaces any fn registered with the same name.
+func RegisterFunc(name string, fn interface{}) {
+	register("func", name, fn)
+}
+
+// LookupFunc returns callback registered with given name.
+func LookupFunc(name string) (interface{}, bool) {
+	return lookup("func", name)
+}
+
+// RegisterFormatter makes formatter type name available to config files,
+// building formatters of that type with factory. console and json formatters
+// are registered by their packages. It replaces any factory registered with
+// the same name.
+func RegisterFormatter(name string, factory FormatterFactory) {
+	register("formatter", name, factory)
+}
+
+// LookupFormatter returns factory of formatter type registered with given name.
+func LookupFormatter(name string) (FormatterFactory, bool) {
+	v, ok := lookup("formatter", name)
+	if !ok {
+		return nil, false
+	}
+	return v.(FormatterFactory), true
+}
+
+// RegisterWriter makes writer type name available to config files, building
+// writers of that type with factory. It replaces any factory registered with
+// the same name.
+func RegisterWriter(name string, factory WriterFactory) {
+	register("writer", name, factory)
+}
+
+// LookupWriter returns factory of writer type registered with given name.
+func LookupWriter(name string) (WriterFactory, bool) {
+	v, ok := lookup("writer", name)
+	if !ok {
+		return nil, false
+	}
+	return v.(WriterFactory), true
+}
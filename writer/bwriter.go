@@ -0,0 +1,293 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/pool"
+)
+
+const (
+	// DefaultFlushSize is flush size of BufferedWriter when 0 is given
+	DefaultFlushSize = 64 * 1024
+	// DefaultFlushInterval is flush interval of BufferedWriter when 0 is given
+	DefaultFlushInterval = time.Second
+)
+
+// ErrWriterClosed is returned on writes to a closed BufferedWriter
+var ErrWriterClosed = errors.New("writer is closed")
+
+// noLevel is level of lines written with Write
+const noLevel nlog.Level = -1
+
+// retainedFlushes is number of flush sizes of lines kept when flushes fail
+const retainedFlushes = 4
+
+// BufferedWriter accumulates writes in a pooled buffer and writes them to
+// wrapped writer in batches, reducing write calls for file writers.
+//
+// Buffer is flushed when it would exceed flush size, every flush interval,
+// after a line of flush level or more severe level is written with
+// WriteIfLevel and on Flush, Sync or Close. Each write given to
+// BufferedWriter is kept whole, batches are cut only between writes, so a log
+// line is never split across rotated files. If wrapped writer is a
+// LeveledWriter, batches hold lines of a single level which are written with
+// WriteIfLevel.
+//
+// Lines of failed flushes are kept for next flush, up to 4 times flush size.
+// Oldest lines are dropped above it, which is reported to ErrorHandler. It is
+// concurrent safe.
+type BufferedWriter struct {
+	// ErrorHandler is called with errors of periodic flushes and lines dropped
+	// while flushes fail, which are printed to stderr if it is nil. It is set
+	// before first write.
+	ErrorHandler func(err error)
+
+	w        io.WriteCloser
+	size     int
+	interval time.Duration
+	level    nlog.Level
+
+	mu     sync.Mutex
+	buf    nlog.Buffer
+	ends   []int        // end offsets of writes in buf
+	lvls   []nlog.Level // levels of writes in buf
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewBufferedWriter returns a new BufferedWriter writing to w. Buffer is
+// flushed when it reaches size bytes, every interval and after lines of
+// flushLevel or more severe. Zero size or interval selects defaults,
+// negative interval disables periodic flushes.
+func NewBufferedWriter(w io.WriteCloser, size int, interval time.Duration, flushLevel nlog.Level) *BufferedWriter {
+	if size <= 0 {
+		size = DefaultFlushSize
+	}
+	if interval == 0 {
+		interval = DefaultFlushInterval
+	}
+	return &BufferedWriter{
+		w:        w,
+		size:     size,
+		interval: interval,
+		level:    flushLevel,
+	}
+}
+
+// Write implements io.Writer. p is buffered as a whole.
+func (b *BufferedWriter) Write(p []byte) (n int, err error) {
+	return b.write(p, noLevel, false)
+}
+
+// WriteIfLevel buffers p, flushing buffer if lvl is flush level or more
+// severe. Level filtering is left to wrapping writers and wrapped writer.
+func (b *BufferedWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	return b.write(p, lvl, lvl <= b.level)
+}
+
+// GetLevel returns level of wrapped writer if it is a LeveledWriter,
+// nlog.DEBUG otherwise.
+func (b *BufferedWriter) GetLevel() nlog.Level {
+	if lw, ok := b.w.(LeveledWriter); ok {
+		return lw.GetLevel()
+	}
+	return nlog.DEBUG
+}
+
+// Enabled returns Enabled of wrapped writer if it is a LeveledWriter, true
+// otherwise.
+func (b *BufferedWriter) Enabled(lvl nlog.Level) bool {
+	if lw, ok := b.w.(LeveledWriter); ok {
+		return lw.Enabled(lvl)
+	}
+	return true
+}
+
+// Flush writes buffered data to wrapped writer.
+func (b *BufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush()
+}
+
+// Sync flushes buffer and calls Sync of wrapped writer if it implements
+// Syncer.
+func (b *BufferedWriter) Sync() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.flush(); err != nil {
+		return err
+	}
+	return SyncWriter(b.w)
+}
+
+// Close flushes buffer, stops periodic flushes and closes wrapped writer.
+func (b *BufferedWriter) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	err := b.flush()
+	b.closed = true
+	if b.buf != nil {
+		pool.PutBuffer(b.buf)
+		b.buf = nil
+	}
+	stop, done := b.stop, b.done
+	b.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	if errClose := b.w.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// write buffers p of level lvl, flushing before if it does not fit and after
+// if forced. p is kept in buffer if flush fails.
+func (b *BufferedWriter) write(p []byte, lvl nlog.Level, forceFlush bool) (n int, err error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, ErrWriterClosed
+	}
+	if b.buf == nil {
+		b.buf = pool.GetBuffer()
+		b.start()
+	}
+	dropped := 0
+	if b.buf.Len() > 0 && b.buf.Len()+len(p) > b.size {
+		if err = b.flush(); err != nil {
+			dropped = b.drop(retainedFlushes*b.size - len(p))
+		}
+	}
+	b.buf.AppendBytes(p)
+	b.ends = append(b.ends, b.buf.Len())
+	b.lvls = append(b.lvls, lvl)
+	if err == nil && (forceFlush || b.buf.Len() >= b.size) {
+		err = b.flush()
+	}
+	b.mu.Unlock()
+	if dropped > 0 {
+		// handler is called without lock, it may log to this writer
+		b.handleError(fmt.Errorf("dropped %d lines, flush failed, err: %v", dropped, err))
+	}
+	// p is buffered even if flush fails, err is of writing buffer
+	return len(p), err
+}
+
+// drop drops oldest writes until buffer holds at most limit bytes and
+// returns number of dropped writes
+func (b *BufferedWriter) drop(limit int) int {
+	n, start := 0, 0
+	for n < len(b.ends) && b.buf.Len()-start > limit {
+		start = b.ends[n]
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	rest := append([]byte(nil), b.buf.Bytes()[start:]...)
+	b.buf.Reset()
+	b.buf.AppendBytes(rest)
+	ends := b.ends[:0]
+	for _, e := range b.ends[n:] {
+		ends = append(ends, e-start)
+	}
+	b.ends = ends
+	b.lvls = append(b.lvls[:0], b.lvls[n:]...)
+	return n
+}
+
+// flush writes buffered data in batches of at most flush size, cutting only
+// between writes, and between writes of different levels if wrapped writer is
+// a LeveledWriter. Writes larger than flush size are written alone.
+func (b *BufferedWriter) flush() error {
+	if b.buf == nil || b.buf.Len() == 0 {
+		return nil
+	}
+	lw, leveled := b.w.(LeveledWriter)
+	data := b.buf.Bytes()
+	start := 0
+	for i := 0; i < len(b.ends); {
+		first := i
+		end := b.ends[i]
+		i++
+		for i < len(b.ends) && b.ends[i]-start <= b.size && (!leveled || b.lvls[i] == b.lvls[first]) {
+			end = b.ends[i]
+			i++
+		}
+		var err error
+		if leveled && b.lvls[first] != noLevel {
+			_, err = lw.WriteIfLevel(b.lvls[first], data[start:end])
+		} else {
+			_, err = b.w.Write(data[start:end])
+		}
+		if err != nil {
+			// keep unwritten batches for next flush
+			rest := append([]byte(nil), data[start:]...)
+			b.buf.Reset()
+			b.buf.AppendBytes(rest)
+			ends := b.ends[:0]
+			for _, e := range b.ends[first:] {
+				ends = append(ends, e-start)
+			}
+			b.ends = ends
+			b.lvls = append(b.lvls[:0], b.lvls[first:]...)
+			return err
+		}
+		start = end
+	}
+	b.buf.Reset()
+	b.ends = b.ends[:0]
+	b.lvls = b.lvls[:0]
+	return nil
+}
+
+// handleError routes errors of periodic flushes to ErrorHandler, printing them
+// to stderr if it is not set.
+func (b *BufferedWriter) handleError(err error) {
+	if b.ErrorHandler != nil {
+		b.ErrorHandler(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "nlog: buffered writer flush failed, err: %v\n", err)
+}
+
+// start starts periodic flushes if enabled.
+func (b *BufferedWriter) start() {
+	if b.interval < 0 || b.stop != nil {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.mu.Lock()
+				err := b.flush()
+				b.mu.Unlock()
+				if err != nil {
+					b.handleError(err)
+				}
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,236 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
+// recorder records each write it receives.
+type recorder struct {
+	mu     sync.Mutex
+	writes []string
+	fail   bool
+	closed bool
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return 0, errors.New("write failed")
+	}
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.writes...)
+}
+
+func TestBufferedWriterSize(t *testing.T) {
+	r := &recorder{}
+	b := NewBufferedWriter(r, 10, -1, nlog.FATAL)
+	defer b.Close()
+	b.Write([]byte("aaaa\n"))
+	b.Write([]byte("bbbb\n"))
+	if got := r.get(); len(got) != 1 || got[0] != "aaaa\nbbbb\n" {
+		t.Fatalf("expected one batch of full buffer, got %q", got)
+	}
+	b.Write([]byte("cccc\n"))
+	b.Write([]byte("dddddd\n"))
+	if got := r.get(); len(got) != 2 || got[1] != "cccc\n" {
+		t.Fatalf("expected buffer flushed before overflowing line, got %q", got)
+	}
+	b.Write([]byte(strings.Repeat("e", 20)))
+	want := []string{"aaaa\nbbbb\n", "cccc\n", "dddddd\n", strings.Repeat("e", 20)}
+	if got := r.get(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestBufferedWriterLevel(t *testing.T) {
+	r := &recorder{}
+	b := NewBufferedWriter(r, 1024, -1, nlog.ERROR)
+	defer b.Close()
+	b.WriteIfLevel(nlog.INFO, []byte("info\n"))
+	if got := r.get(); len(got) != 0 {
+		t.Fatalf("expected no writes, got %q", got)
+	}
+	b.WriteIfLevel(nlog.ERROR, []byte("error\n"))
+	if got := r.get(); len(got) != 1 || got[0] != "info\nerror\n" {
+		t.Fatalf("expected flush on error, got %q", got)
+	}
+}
+
+func TestBufferedWriterInterval(t *testing.T) {
+	r := &recorder{}
+	b := NewBufferedWriter(r, 1024, 10*time.Millisecond, nlog.FATAL)
+	defer b.Close()
+	b.Write([]byte("line\n"))
+	deadline := time.Now().Add(2 * time.Second)
+	for len(r.get()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("buffer not flushed by interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := r.get(); got[0] != "line\n" {
+		t.Fatalf("expected %q, got %q", "line\n", got[0])
+	}
+}
+
+func TestBufferedWriterFlushBatches(t *testing.T) {
+	r := &recorder{}
+	b := NewBufferedWriter(r, 12, -1, nlog.FATAL)
+	r.fail = true
+	b.Write([]byte("aaaa\n"))
+	b.Write([]byte("bbbb\n"))
+	if err := b.Flush(); err == nil {
+		t.Fatal("expected flush error")
+	}
+	r.fail = false
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	b.Write([]byte("cccc\n"))
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(r.get(), ""); got != "aaaa\nbbbb\ncccc\n" {
+		t.Fatalf("expected lines kept after failed flush, got %q", got)
+	}
+	if !r.closed {
+		t.Fatal("expected wrapped writer closed")
+	}
+	if _, err := b.Write([]byte("x")); err != ErrWriterClosed {
+		t.Fatalf("expected ErrWriterClosed, got %v", err)
+	}
+}
+
+func TestBufferedWriterDropOnFailure(t *testing.T) {
+	r := &recorder{fail: true}
+	b := NewBufferedWriter(r, 10, -1, nlog.FATAL)
+	var errs []error
+	b.ErrorHandler = func(err error) { errs = append(errs, err) }
+	for i := 0; i < 100; i++ {
+		b.Write([]byte(fmt.Sprintf("%04d\n", i)))
+	}
+	b.mu.Lock()
+	size := b.buf.Len()
+	b.mu.Unlock()
+	if size > retainedFlushes*10 {
+		t.Fatalf("expected at most %d bytes kept, got %d", retainedFlushes*10, size)
+	}
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "dropped") {
+		t.Fatalf("expected dropped lines reported, got %v", errs)
+	}
+	r.mu.Lock()
+	r.fail = false
+	r.mu.Unlock()
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(r.get(), "")
+	if !strings.HasSuffix(got, "0099\n") || len(got) != size {
+		t.Fatalf("expected newest lines written, got %q", got)
+	}
+}
+
+func TestBufferedWriterNoSplit(t *testing.T) {
+	var out bytes.Buffer
+	r := &recorder{}
+	b := NewBufferedWriter(r, 16, -1, nlog.FATAL)
+	for i := 0; i < 50; i++ {
+		line := strings.Repeat("x", i%9) + "\n"
+		out.WriteString(line)
+		b.Write([]byte(line))
+	}
+	b.Close()
+	var got bytes.Buffer
+	for _, w := range r.get() {
+		if !strings.HasSuffix(w, "\n") {
+			t.Fatalf("expected batches of whole lines, got %q", w)
+		}
+		got.WriteString(w)
+	}
+	if got.String() != out.String() {
+		t.Fatalf("expected %q, got %q", out.String(), got.String())
+	}
+}
+
+// levelRecorder records levels of writes it receives.
+type levelRecorder struct {
+	recorder
+	lvls []nlog.Level
+}
+
+func (r *levelRecorder) WriteIfLevel(lvl nlog.Level, p []byte) (int, error) {
+	r.mu.Lock()
+	r.lvls = append(r.lvls, lvl)
+	r.mu.Unlock()
+	return r.Write(p)
+}
+
+func (r *levelRecorder) GetLevel() nlog.Level    { return nlog.DEBUG }
+func (r *levelRecorder) Enabled(nlog.Level) bool { return true }
+func (r *levelRecorder) Sync() error             { return nil }
+
+func TestBufferedWriterLeveled(t *testing.T) {
+	r := &levelRecorder{}
+	b := NewBufferedWriter(r, 1024, -1, nlog.ERROR)
+	b.WriteIfLevel(nlog.INFO, []byte("info\n"))
+	b.WriteIfLevel(nlog.INFO, []byte("info\n"))
+	b.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	b.WriteIfLevel(nlog.ERROR, []byte("error\n"))
+	b.Write([]byte("plain\n"))
+	b.Close()
+	want := []string{"info\ninfo\n", "debug\n", "error\n", "plain\n"}
+	if got := r.get(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected batches split by level %q, got %q", want, got)
+	}
+	if wantLvls := []nlog.Level{nlog.INFO, nlog.DEBUG, nlog.ERROR}; fmt.Sprint(r.lvls) != fmt.Sprint(wantLvls) {
+		t.Fatalf("expected levels %v passed to wrapped writer, got %v", wantLvls, r.lvls)
+	}
+}
+
+func TestBufferedWriterIntervalError(t *testing.T) {
+	r := &recorder{fail: true}
+	b := NewBufferedWriter(r, 1024, 10*time.Millisecond, nlog.FATAL)
+	defer b.Close()
+	errs := make(chan error, 10)
+	b.ErrorHandler = func(err error) { errs <- err }
+	b.Write([]byte("aaaa\n"))
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected error of periodic flush")
+	}
+	r.mu.Lock()
+	r.fail = false
+	r.mu.Unlock()
+	if n, err := b.Write([]byte("bbbb\n")); n != 5 || err != nil {
+		t.Fatalf("expected write after failed flush to succeed, got %d %v", n, err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(r.get(), ""); got != "aaaa\nbbbb\n" {
+		t.Fatalf("expected lines kept after failed flush, got %q", got)
+	}
+}
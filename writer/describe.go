@@ -0,0 +1,114 @@
+package writer
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/derkan/nlog"
+)
+
+// Info describes a writer and writers wrapped by it, see Describe
+type Info struct {
+	// Type is type of writer, like stdout, filerotator or buffered. It is Go
+	// type name of writers which don't implement Describer.
+	Type string `json:"type" yaml:"type"`
+	// Name is file name of file writers or address of network writers
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Levels are names of levels written, separated by commas. Empty if
+	// writer does not filter levels.
+	Levels string `json:"levels,omitempty" yaml:"levels,omitempty"`
+	// Loggers are logger name prefixes of lines written by a route writer
+	Loggers []string `json:"loggers,omitempty" yaml:"loggers,omitempty"`
+	// Match describes field rule of lines written by a route writer, see
+	// Route.MatchInfo
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Final is true for route writers stopping routing of their lines
+	Final bool `json:"final,omitempty" yaml:"final,omitempty"`
+	// Writers are writers wrapped by writer
+	Writers []Info `json:"writers,omitempty" yaml:"writers,omitempty"`
+}
+
+// Describer is implemented by writers which describe themselves for Describe
+type Describer interface {
+	Describe() Info
+}
+
+// Describe returns info of w and writers wrapped by it, for diagnostics
+func Describe(w io.Writer) Info {
+	switch w := w.(type) {
+	case Describer:
+		return w.Describe()
+	case *os.File:
+		switch w {
+		case os.Stdout:
+			return Info{Type: "stdout"}
+		case os.Stderr:
+			return Info{Type: "stderr"}
+		}
+		return Info{Type: "file", Name: w.Name()}
+	}
+	return Info{Type: fmt.Sprintf("%T", w)}
+}
+
+// Describe returns info of w with levels it writes
+func (l *Writer) Describe() Info {
+	info := Describe(l.w)
+	info.Levels = l.s.String()
+	return info
+}
+
+// Describe returns info of w with levels it writes
+func (l *ParallelWriter) Describe() Info {
+	info := Describe(l.w)
+	info.Levels = l.s.String()
+	return info
+}
+
+// Describe returns info of writers
+func (t *MultiWriter) Describe() Info {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info := Info{Type: "multi"}
+	for _, w := range t.writers {
+		info.Writers = append(info.Writers, w.Describe())
+	}
+	return info
+}
+
+// Describe returns info of writers
+func (t *ParallelMultiWriter) Describe() Info {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	info := Info{Type: "parallel"}
+	for _, w := range t.writers {
+		info.Writers = append(info.Writers, w.Describe())
+	}
+	return info
+}
+
+// Describe returns info of buffered writer
+func (b *BufferedWriter) Describe() Info {
+	return Info{Type: "buffered", Writers: []Info{Describe(b.w)}}
+}
+
+// Describe returns info of ring writer, with levels written directly
+func (r *RingWriter) Describe() Info {
+	return Info{Type: "ring", Levels: LevelRange(nlog.FATAL, r.level).String(), Writers: []Info{Describe(r.w)}}
+}
+
+// Describe returns info of writers of routes with rules of their routes
+func (r *RouterWriter) Describe() Info {
+	info := Info{Type: "route"}
+	for _, route := range r.routes {
+		w := Describe(route.Writer)
+		w.Loggers = route.Loggers
+		w.Match = route.MatchInfo
+		if w.Match == "" && route.Match != nil {
+			w.Match = "func"
+		}
+		w.Final = route.Final
+		info.Writers = append(info.Writers, w)
+	}
+	return info
+}
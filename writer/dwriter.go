@@ -22,6 +22,16 @@ func (l *DummyLeveledWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err
 	return
 }
 
+// Sync does nothing
+func (l *DummyLeveledWriter) Sync() error {
+	return nil
+}
+
+// Enabled returns false, no level is written
+func (l *DummyLeveledWriter) Enabled(lvl nlog.Level) bool {
+	return false
+}
+
 // GetLevel returns log level
 func (l *DummyLeveledWriter) GetLevel() nlog.Level {
 	return nlog.FATAL
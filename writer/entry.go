@@ -0,0 +1,242 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/derkan/nlog"
+)
+
+// Field is a key=value pair found in a log line
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Entry is a formatted log line split into its parts
+type Entry struct {
+	// Time is time string as written by formatter
+	Time string
+	// Level is level string as written by formatter
+	Level string
+	// Logger is sub logger name
+	Logger string
+	// Message is log message
+	Message string
+	// Fields holds remaining fields in written order
+	Fields []Field
+}
+
+// JSONKeys are key names of parts of lines written by json formatter
+type JSONKeys struct {
+	Time    string
+	Level   string
+	Logger  string
+	Message string
+}
+
+// jsonKeys holds JSONKeys lines are parsed with, see SetJSONKeys
+var jsonKeys atomic.Value
+
+func init() {
+	jsonKeys.Store(JSONKeys{Time: "time", Level: "level", Logger: "logger", Message: "msg"})
+}
+
+// SetJSONKeys sets key names ParseJSONLine splits lines with. json formatter
+// sets them to its TimeKey, LevelKey, NameKey and MsgKey when it is built.
+func SetJSONKeys(keys JSONKeys) {
+	jsonKeys.Store(keys)
+}
+
+// GetJSONKeys returns key names ParseJSONLine splits lines with
+func GetJSONKeys() JSONKeys {
+	return jsonKeys.Load().(JSONKeys)
+}
+
+// ParseJSONLine splits a line written by json formatter into an Entry, using
+// key names of GetJSONKeys. Nested objects and arrays are kept as raw JSON text
+// in field values. Returns false if p is not a JSON object.
+func ParseJSONLine(p []byte) (e Entry, ok bool) {
+	keys := GetJSONKeys()
+	p = bytes.TrimSpace(p)
+	if len(p) < 2 || p[0] != '{' {
+		return e, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return e, false
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return e, false
+		}
+		key, isStr := t.(string)
+		if !isStr {
+			return e, false
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return e, false
+		}
+		val := string(raw)
+		if len(raw) > 0 && raw[0] == '"' {
+			if err := json.Unmarshal(raw, &val); err != nil {
+				return e, false
+			}
+		}
+		switch key {
+		case keys.Time:
+			e.Time = val
+		case keys.Level:
+			e.Level = val
+		case keys.Logger:
+			e.Logger = val
+		case keys.Message:
+			e.Message = val
+		default:
+			e.Fields = append(e.Fields, Field{Key: key, Value: val})
+		}
+	}
+	return e, true
+}
+
+// LevelFromString returns level for given level string as written by
+// formatters. Returns false if str is not a known level.
+func LevelFromString(str string) (nlog.Level, bool) {
+	str = strings.TrimSpace(str)
+	for lvl, name := range nlog.LevelNames {
+		if name == str {
+			return lvl, true
+		}
+	}
+	return nlog.INFO, false
+}
+
+// ParseLine splits a line written by json or console formatters into an Entry.
+func ParseLine(p []byte) (e Entry, ok bool) {
+	if e, ok = ParseJSONLine(p); ok {
+		return e, true
+	}
+	return ParseConsoleLine(p)
+}
+
+// maxTimeTokens is the maximum number of space separated time tokens before
+// level in console lines, like date and time.
+const maxTimeTokens = 3
+
+// ParseConsoleLine splits a line written by console formatter into an Entry,
+// removing colors. Fields are found as trailing key=value pairs, so a message
+// containing such pairs is split as fields too. Returns false if no level
+// could be found in p.
+func ParseConsoleLine(p []byte) (e Entry, ok bool) {
+	line := stripColors(strings.TrimRight(string(p), "\r\n"))
+	rest := line
+	for i := 0; i <= maxTimeTokens; i++ {
+		tok := rest
+		next := ""
+		if sp := strings.IndexByte(rest, ' '); sp >= 0 {
+			tok, next = rest[:sp], rest[sp+1:]
+		}
+		if _, isLevel := LevelFromString(tok); isLevel {
+			e.Time = strings.TrimSpace(line[:len(line)-len(rest)])
+			e.Level = tok
+			rest = next
+			ok = true
+			break
+		}
+		if next == "" {
+			break
+		}
+		rest = next
+	}
+	if !ok {
+		return e, false
+	}
+	if strings.HasPrefix(rest, "[") {
+		if end := strings.IndexByte(rest, ']'); end > 0 {
+			e.Logger = rest[1:end]
+			rest = strings.TrimPrefix(rest[end+1:], " ")
+		}
+	}
+	start := fieldStart(rest, 0)
+	e.Message = strings.TrimSpace(rest[:start])
+	for start < len(rest) {
+		eq := strings.IndexByte(rest[start:], '=') + start
+		key := rest[start:eq]
+		val, n := fieldValue(rest[eq+1:])
+		e.Fields = append(e.Fields, Field{Key: key, Value: val})
+		start = fieldStart(rest, eq+1+n)
+	}
+	return e, true
+}
+
+// fieldStart returns start of first key=value pair in s after from, or
+// len(s) if there is none.
+func fieldStart(s string, from int) int {
+	for i := from; i < len(s); i++ {
+		if i > 0 && s[i-1] != ' ' {
+			continue
+		}
+		j := i
+		for j < len(s) && isKeyChar(s[j]) {
+			j++
+		}
+		if j > i && j < len(s) && s[j] == '=' {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// fieldValue returns value at start of s and its length, unquoting quoted
+// values.
+func fieldValue(s string) (string, int) {
+	if strings.HasPrefix(s, `"`) {
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				if v, err := strconv.Unquote(s[:i+1]); err == nil {
+					return v, i + 1
+				}
+				return s[1:i], i + 1
+			}
+		}
+	}
+	if sp := strings.IndexByte(s, ' '); sp >= 0 {
+		return s[:sp], sp
+	}
+	return s, len(s)
+}
+
+// isKeyChar reports whether c can be a part of field key in console lines.
+func isKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+// stripColors removes ANSI color sequences from s.
+func stripColors(s string) string {
+	if strings.IndexByte(s, '\x1b') < 0 {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+			j := i + 2
+			for j < len(s) && (s[j] < '@' || s[j] > '~') {
+				j++
+			}
+			i = j
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,346 @@
+package filerotater
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBackupFormat is the backup name template used when BackupFormat is
+// empty. It gives names like `server-2016-11-04T18-30-00.000.log`.
+const DefaultBackupFormat = "{name}-%Y-%m-%dT%H-%M-%S.%L{ext}"
+
+// backupLayout is a compiled backup path template. It builds backup names
+// and finds backups matching the template.
+type backupLayout struct {
+	// dir is the backup directory template, or a plain directory if static
+	dir    string
+	static bool
+	// root is the deepest directory of dir without any tokens
+	root string
+	// depth is the number of directories from root to backups
+	depth  int
+	format string
+	name   string
+	ext    string
+	hasSeq bool
+	loc    *time.Location
+	// current is the log file, never reported as a backup
+	current string
+
+	re     *regexp.Regexp
+	groups []string
+}
+
+// layout compiles backup layout of Rotater.
+func (l *Rotater) layout() (*backupLayout, error) {
+	name, ext := l.nameAndExt()
+	b := &backupLayout{
+		dir:     l.dir(),
+		static:  true,
+		format:  l.BackupFormat,
+		name:    name,
+		ext:     ext,
+		loc:     time.UTC,
+		current: l.filename(),
+	}
+	if b.format == "" {
+		b.format = DefaultBackupFormat
+	}
+	if strings.ContainsRune(b.format, filepath.Separator) || strings.ContainsRune(b.format, '/') {
+		return nil, fmt.Errorf("backup format %q can't contain path separators", b.format)
+	}
+	if l.LocalTime {
+		b.loc = time.Local
+	}
+	b.hasSeq = strings.Contains(b.format, "{seq}")
+	b.root = b.dir
+
+	pattern := regexp.QuoteMeta(filepath.ToSlash(b.dir))
+	if l.BackupDir != "" {
+		b.dir = l.BackupDir
+		if !filepath.IsAbs(b.dir) {
+			b.dir = filepath.Join(l.dir(), b.dir)
+		}
+		b.dir = filepath.Clean(b.dir)
+		i := strings.IndexAny(b.dir, "%{")
+		b.static = i < 0
+		if b.static {
+			b.root = b.dir
+			pattern = regexp.QuoteMeta(filepath.ToSlash(b.dir))
+		} else {
+			b.root = filepath.Dir(b.dir[:i] + "x")
+			rel, _ := filepath.Rel(b.root, b.dir)
+			b.depth = len(strings.Split(rel, string(filepath.Separator)))
+			var dirPattern string
+			dirPattern, b.groups = b.pattern(filepath.ToSlash(b.dir), b.groups)
+			pattern = dirPattern
+		}
+	}
+	namePattern, groups := b.pattern(b.format, b.groups)
+	b.groups = groups
+	if !b.hasSeq {
+		// sequence is appended to names colliding with an existing backup
+		namePattern += `(?:\.(\d+))?`
+		b.groups = append(b.groups, "{seq}")
+	}
+	suffixes := codecSuffixes()
+	for i := range suffixes {
+		suffixes[i] = regexp.QuoteMeta(suffixes[i])
+	}
+	re, err := regexp.Compile("^" + pattern + "/" + namePattern + "(" + strings.Join(suffixes, "|") + ")?$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup format %q: %s", b.format, err)
+	}
+	b.re = re
+	return b, nil
+}
+
+// timeTokens maps strftime style tokens to their time formats and patterns.
+// %L is milliseconds, which has no time format without a leading dot.
+var timeTokens = map[byte]struct {
+	format  string
+	pattern string
+}{
+	'Y': {"2006", `(\d{4})`},
+	'y': {"06", `(\d{2})`},
+	'm': {"01", `(\d{2})`},
+	'd': {"02", `(\d{2})`},
+	'H': {"15", `(\d{2})`},
+	'M': {"04", `(\d{2})`},
+	'S': {"05", `(\d{2})`},
+	'L': {"", `(\d{3})`},
+}
+
+// expand returns tmpl with its tokens replaced using t and seq.
+func (b *backupLayout) expand(tmpl string, t time.Time, seq int) string {
+	var sb strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if c == '%' && i+1 < len(tmpl) {
+			if tok, ok := timeTokens[tmpl[i+1]]; ok {
+				if tmpl[i+1] == 'L' {
+					sb.WriteString(fmt.Sprintf("%03d", t.Nanosecond()/int(time.Millisecond)))
+				} else {
+					sb.WriteString(t.Format(tok.format))
+				}
+				i++
+				continue
+			}
+			if tmpl[i+1] == '%' {
+				sb.WriteByte('%')
+				i++
+				continue
+			}
+		}
+		if c == '{' {
+			if v, n, ok := b.placeholder(tmpl[i:], seq); ok {
+				sb.WriteString(v)
+				i += n - 1
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// placeholder returns value and length of placeholder at start of s.
+func (b *backupLayout) placeholder(s string, seq int) (string, int, bool) {
+	switch {
+	case strings.HasPrefix(s, "{name}"):
+		return b.name, len("{name}"), true
+	case strings.HasPrefix(s, "{ext}"):
+		return b.ext, len("{ext}"), true
+	case strings.HasPrefix(s, "{seq}"):
+		return strconv.Itoa(seq), len("{seq}"), true
+	}
+	return "", 0, false
+}
+
+// pattern returns regular expression matching tmpl, appending captured tokens
+// to groups.
+func (b *backupLayout) pattern(tmpl string, groups []string) (string, []string) {
+	var sb strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if c == '%' && i+1 < len(tmpl) {
+			if tok, ok := timeTokens[tmpl[i+1]]; ok {
+				sb.WriteString(tok.pattern)
+				groups = append(groups, tmpl[i:i+2])
+				i++
+				continue
+			}
+			if tmpl[i+1] == '%' {
+				sb.WriteByte('%')
+				i++
+				continue
+			}
+		}
+		if c == '{' {
+			if v, n, ok := b.placeholder(tmpl[i:], 0); ok {
+				if tmpl[i:i+n] == "{seq}" {
+					sb.WriteString(`(\d+)`)
+					groups = append(groups, "{seq}")
+				} else {
+					sb.WriteString(regexp.QuoteMeta(v))
+				}
+				i += n - 1
+				continue
+			}
+		}
+		sb.WriteString(regexp.QuoteMeta(string(c)))
+	}
+	return sb.String(), groups
+}
+
+// backupName returns an unused backup path for a rotation at time t. When
+// the format has no {seq}, a sequence number is only appended to names
+// colliding with an existing backup.
+func (b *backupLayout) backupName(t time.Time) string {
+	t = t.In(b.loc)
+	dir := b.dir
+	if !b.static {
+		dir = b.expand(b.dir, t, 0)
+	}
+	for seq := 1; ; seq++ {
+		name := b.expand(b.format, t, seq)
+		if !b.hasSeq && seq > 1 {
+			name += "." + strconv.Itoa(seq-1)
+		}
+		path := filepath.Join(dir, name)
+		if !fileExists(path) {
+			return path
+		}
+	}
+}
+
+// parse returns rotation time and sequence number of backup at path.
+func (b *backupLayout) parse(path string) (time.Time, int, error) {
+	m := b.re.FindStringSubmatch(filepath.ToSlash(path))
+	if m == nil {
+		return time.Time{}, 0, errors.New("mismatched backup name")
+	}
+	year, month, day := 1970, 1, 1
+	var hour, minute, sec, msec, seq int
+	hasTime := false
+	for i, tok := range b.groups {
+		v := m[i+1]
+		if v == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(v)
+		switch tok {
+		case "{seq}":
+			seq = n
+			continue
+		case "%Y":
+			year = n
+		case "%y":
+			year = 2000 + n
+		case "%m":
+			month = n
+		case "%d":
+			day = n
+		case "%H":
+			hour = n
+		case "%M":
+			minute = n
+		case "%S":
+			sec = n
+		case "%L":
+			msec = n
+		}
+		hasTime = true
+	}
+	if !hasTime {
+		return time.Time{}, seq, errNoTime
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || sec > 59 {
+		return time.Time{}, 0, errors.New("invalid time in backup name")
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, sec, msec*int(time.Millisecond), b.loc), seq, nil
+}
+
+// suffix returns compression suffix of backup at path.
+func (b *backupLayout) suffix(path string) string {
+	m := b.re.FindStringSubmatch(filepath.ToSlash(path))
+	if m == nil {
+		return ""
+	}
+	return m[len(m)-1]
+}
+
+// errNoTime is returned by parse for layouts without time tokens.
+var errNoTime = errors.New("no time in backup name")
+
+// find returns backups matching the layout, sorted by newest first.
+func (b *backupLayout) find() ([]logInfo, error) {
+	var logFiles []logInfo
+	add := func(path string, info os.FileInfo) {
+		if path == b.current {
+			return
+		}
+		t, seq, err := b.parse(path)
+		if err == errNoTime {
+			// use modification time for layouts without time in names
+			t = info.ModTime()
+		} else if err != nil {
+			// error parsing means that the file was not generated by
+			// filerotater, and therefore it's not a backup file.
+			return
+		}
+		logFiles = append(logFiles, logInfo{timestamp: t, seq: seq, path: path, suffix: b.suffix(path), FileInfo: info})
+	}
+
+	if b.static {
+		files, err := ioutil.ReadDir(b.dir)
+		if err != nil {
+			if os.IsNotExist(err) && b.dir != b.root {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("can't read log file directory: %s", err)
+		}
+		for _, f := range files {
+			if !f.IsDir() {
+				add(filepath.Join(b.dir, f.Name()), f)
+			}
+		}
+	} else {
+		err := filepath.Walk(b.root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if path == b.root && os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if info.IsDir() {
+				if rel, _ := filepath.Rel(b.root, path); rel != "." && len(strings.Split(rel, string(filepath.Separator))) > b.depth {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			add(path, info)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't read backup directory: %s", err)
+		}
+	}
+
+	sort.Sort(byFormatTime(logFiles))
+	return logFiles, nil
+}
+
+// fileExists reports whether a file exists at path.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
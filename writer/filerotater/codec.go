@@ -0,0 +1,127 @@
+package filerotater
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Codec compresses rotated log files. Codecs are selected by name with
+// Rotater.Compression after being registered with RegisterCodec.
+//
+// Only gzip, zlib and deflate are built in, to keep filerotater free of
+// dependencies. Other formats like zstd, xz, snappy or lz4 can be plugged in
+// by registering a codec wrapping their writers:
+//
+//	filerotater.RegisterCodec(filerotater.NewCodec("zstd", ".zst",
+//		func(w io.Writer, level int) (io.WriteCloser, error) {
+//			return zstd.NewWriter(w)
+//		}))
+type Codec interface {
+	// Name is name of codec used in configs, like gzip.
+	Name() string
+
+	// Suffix is appended to names of compressed files, like .gz.
+	Suffix() string
+
+	// NewWriter returns a writer compressing to w. Level is codec specific,
+	// 0 selects default level of the codec.
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+}
+
+// DefaultCodec is name of codec used when Rotater.Compression is empty.
+const DefaultCodec = "gzip"
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+func init() {
+	RegisterCodec(NewCodec("gzip", compressSuffix, func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, flateLevel(level))
+	}))
+	RegisterCodec(NewCodec("zlib", ".zz", func(w io.Writer, level int) (io.WriteCloser, error) {
+		return zlib.NewWriterLevel(w, flateLevel(level))
+	}))
+	RegisterCodec(NewCodec("deflate", ".deflate", func(w io.Writer, level int) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flateLevel(level))
+	}))
+}
+
+// flateLevel maps level 0 to default compression level of deflate based
+// codecs.
+func flateLevel(level int) int {
+	if level == 0 {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+// RegisterCodec makes codec available by its name, replacing any codec
+// registered with the same name.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+// LookupCodec returns registered codec with given name.
+func LookupCodec(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if name == "" {
+		name = DefaultCodec
+	}
+	if c, ok := codecs[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown compression codec %q", name)
+}
+
+// codecSuffixes returns suffixes of registered codecs, longest first so
+// suffixes sharing an ending are matched correctly.
+func codecSuffixes() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	suffixes := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		suffixes = append(suffixes, c.Suffix())
+	}
+	sort.Slice(suffixes, func(i, j int) bool {
+		if len(suffixes[i]) != len(suffixes[j]) {
+			return len(suffixes[i]) > len(suffixes[j])
+		}
+		return suffixes[i] < suffixes[j]
+	})
+	return suffixes
+}
+
+// NewCodec returns a Codec with given name and suffix using fn to create
+// compressing writers.
+func NewCodec(name, suffix string, fn func(w io.Writer, level int) (io.WriteCloser, error)) Codec {
+	return &funcCodec{name: name, suffix: suffix, fn: fn}
+}
+
+// funcCodec is a Codec built from a writer constructor.
+type funcCodec struct {
+	name   string
+	suffix string
+	fn     func(w io.Writer, level int) (io.WriteCloser, error)
+}
+
+func (c *funcCodec) Name() string {
+	return c.name
+}
+
+func (c *funcCodec) Suffix() string {
+	return c.suffix
+}
+
+func (c *funcCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return c.fn(w, level)
+}
@@ -1,5 +1,11 @@
 package filerotater
 
+import (
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
 // Option is function type used for setting console logging config attributes
 type option func(*Rotater)
 
@@ -44,6 +50,31 @@ func WithCompress() option {
 	}
 }
 
+// WithCompression compresses rotated log files with codec registered by
+// given name, like gzip, zlib or deflate.
+func WithCompression(name string) option {
+	return func(c *Rotater) {
+		c.Compress = true
+		c.Compression = name
+	}
+}
+
+// WithCompressLevel sets compression level passed to codec. The default is to
+// use default level of the codec.
+func WithCompressLevel(level int) option {
+	return func(c *Rotater) {
+		c.CompressLevel = level
+	}
+}
+
+// WithMaxTotalSize sets the maximum total size in megabytes of backup files.
+// Oldest backups are removed when it is exceeded.
+func WithMaxTotalSize(size int) option {
+	return func(c *Rotater) {
+		c.MaxTotalSize = size
+	}
+}
+
 // WithUTC determines if the time used for formatting the timestamps in
 // backup files is the computer's local time. The default is to use local
 // time.
@@ -53,6 +84,112 @@ func WithUTC() option {
 	}
 }
 
+// WithRotateEvery sets period of time based rotation aligned to wall-clock
+// boundaries, for example time.Hour for hourly rotation. The default is to
+// rotate only on size.
+func WithRotateEvery(every time.Duration) option {
+	return func(c *Rotater) {
+		c.RotateEvery = every
+	}
+}
+
+// WithBackupFormat sets name template of backup files. See
+// Rotater.BackupFormat for available tokens. It defaults to
+// DefaultBackupFormat.
+func WithBackupFormat(format string) option {
+	return func(c *Rotater) {
+		c.BackupFormat = format
+	}
+}
+
+// WithBackupDir sets directory template to move backup files into. It
+// defaults to directory of log file.
+func WithBackupDir(dir string) option {
+	return func(c *Rotater) {
+		c.BackupDir = dir
+	}
+}
+
+// WithPostRotate sets callback called with final path of each backup after
+// it is compressed if compression is enabled.
+func WithPostRotate(fn func(path string)) option {
+	return func(c *Rotater) {
+		c.PostRotate = fn
+	}
+}
+
+// WithOnCompressed sets callback called when a backup is compressed.
+func WithOnCompressed(fn func(src, dst string)) option {
+	return func(c *Rotater) {
+		c.OnCompressed = fn
+	}
+}
+
+// WithErrorHandler sets handler of errors in compression and removal of old
+// log files. They are printed to stderr by default.
+func WithErrorHandler(fn func(err error)) option {
+	return func(c *Rotater) {
+		c.ErrorHandler = fn
+	}
+}
+
+// WithWatchInterval enables reopening log file when it is moved, removed or
+// truncated externally, checking it at most once per interval.
+func WithWatchInterval(interval time.Duration) option {
+	return func(c *Rotater) {
+		c.WatchInterval = interval
+	}
+}
+
+// WithSyncEvery makes Rotater fsync log file after every n writes.
+func WithSyncEvery(n int) option {
+	return func(c *Rotater) {
+		c.SyncEvery = n
+	}
+}
+
+// WithSyncInterval makes Rotater fsync log file on first write after
+// interval has passed since last fsync.
+func WithSyncInterval(interval time.Duration) option {
+	return func(c *Rotater) {
+		c.SyncInterval = interval
+	}
+}
+
+// WithSyncLevel makes Rotater fsync log file after writing lines of given
+// level or more severe levels.
+func WithSyncLevel(lvl nlog.Level) option {
+	return func(c *Rotater) {
+		c.SyncOnLevel = true
+		c.SyncLevel = lvl
+	}
+}
+
+// WithDSync opens log files with O_DSYNC, so writes return only after data
+// reaches stable storage.
+func WithDSync() option {
+	return func(c *Rotater) {
+		c.DSync = true
+	}
+}
+
+// WithMinFreeSpace makes Rotater delete oldest backups when free space on
+// filesystem of log file is less than given megabytes.
+func WithMinFreeSpace(size int) option {
+	return func(c *Rotater) {
+		c.MinFreeSpace = size
+	}
+}
+
+// WithLowSpaceLevel makes Rotater drop lines less severe than given level
+// while free space is below MinFreeSpace. FATAL selects WARNING.
+func WithLowSpaceLevel(lvl nlog.Level) option {
+	return func(c *Rotater) {
+		c.DropOnLowSpace = true
+		c.LowSpaceLevel = lvl
+	}
+}
+
 // NewFileRotater returns a new instance of Rotater
 func NewFileRotater(opts ...option) *Rotater {
 	i := &Rotater{LocalTime: true}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package filerotater
+
+// diskFree is not supported anywhere but linux, so MinFreeSpace is ignored.
+func diskFree(dir string) (uint64, error) {
+	return 0, errDiskFreeUnsupported
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package filerotater
+
+import "os"
+
+// oDSync is flag used for opening log files with DSync.
+const oDSync = os.O_SYNC
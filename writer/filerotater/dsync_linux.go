@@ -0,0 +1,6 @@
+package filerotater
+
+import "syscall"
+
+// oDSync is flag used for opening log files with DSync.
+const oDSync = syscall.O_DSYNC
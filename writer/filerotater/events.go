@@ -0,0 +1,137 @@
+package filerotater
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventType is type of a rotation event
+type EventType int
+
+const (
+	// Rotated is sent for each backup after the mill processed it. Path is
+	// final path of backup, compressed one if compression is enabled.
+	Rotated EventType = iota
+	// Compressed is sent when Source is compressed into Path.
+	Compressed
+	// Removed is sent when Path is removed by retention rules.
+	Removed
+)
+
+// String returns name of event type
+func (e EventType) String() string {
+	switch e {
+	case Rotated:
+		return "rotated"
+	case Compressed:
+		return "compressed"
+	case Removed:
+		return "removed"
+	}
+	return fmt.Sprintf("EventType(%d)", int(e))
+}
+
+// eventsBufferSize is capacity of channel returned by Events.
+const eventsBufferSize = 64
+
+// Event describes a change made to backups by Rotater.
+type Event struct {
+	Type EventType
+	// Path is path of backup
+	Path string
+	// Source is uncompressed backup for Compressed events
+	Source string
+	Time   time.Time
+}
+
+// hooks holds callbacks and event channel state of Rotater.
+type hooks struct {
+	mu      sync.Mutex
+	events  chan Event
+	rotated []string
+}
+
+// Events returns a channel receiving rotation events. The channel is created
+// on first call and is never closed. Events are dropped when it is full, so
+// a slow reader never blocks logging.
+func (l *Rotater) Events() <-chan Event {
+	l.hooks.mu.Lock()
+	defer l.hooks.mu.Unlock()
+	if l.hooks.events == nil {
+		l.hooks.events = make(chan Event, eventsBufferSize)
+	}
+	return l.hooks.events
+}
+
+// emit sends event to Events channel if it was requested.
+func (l *Rotater) emit(typ EventType, path, source string) {
+	l.hooks.mu.Lock()
+	ch := l.hooks.events
+	l.hooks.mu.Unlock()
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- Event{Type: typ, Path: path, Source: source, Time: currentTime()}:
+	default:
+	}
+}
+
+// addRotated records a new backup to be reported by the mill.
+func (l *Rotater) addRotated(path string) {
+	l.hooks.mu.Lock()
+	l.hooks.rotated = append(l.hooks.rotated, path)
+	l.hooks.mu.Unlock()
+}
+
+// postRotate reports backups rotated since last run to PostRotate and Events
+// with their final paths.
+func (l *Rotater) postRotate() {
+	l.hooks.mu.Lock()
+	rotated := l.hooks.rotated
+	l.hooks.rotated = nil
+	l.hooks.mu.Unlock()
+
+	var suffix string
+	if l.Compress {
+		if codec, err := LookupCodec(l.Compression); err == nil {
+			suffix = codec.Suffix()
+		}
+	}
+	for _, path := range rotated {
+		if !fileExists(path) {
+			if suffix == "" || !fileExists(path+suffix) {
+				// removed by retention rules
+				continue
+			}
+			path += suffix
+		}
+		if l.PostRotate != nil {
+			l.PostRotate(path)
+		}
+		l.emit(Rotated, path, "")
+	}
+}
+
+// compressed reports a compressed backup to OnCompressed and Events.
+func (l *Rotater) compressed(src, dst string) {
+	if l.OnCompressed != nil {
+		l.OnCompressed(src, dst)
+	}
+	l.emit(Compressed, dst, src)
+}
+
+// handleError routes mill errors to ErrorHandler, printing them to stderr
+// if it is not set.
+func (l *Rotater) handleError(err error) {
+	if err == nil {
+		return
+	}
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "filerotater: %v\n", err)
+}
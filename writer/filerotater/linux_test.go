@@ -3,7 +3,12 @@
 package filerotater
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -167,6 +172,8 @@ func TestCompressMaintainOwner(t *testing.T) {
 	// we need to wait a little bit since the files get compressed on a different
 	// goroutine.
 	<-time.After(10 * time.Millisecond)
+	// wait for the mill to be done with fake fs
+	isNil(l.Close(), t)
 
 	// a compressed version of the log file should now exist with the correct
 	// owner.
@@ -181,6 +188,7 @@ type fakeFile struct {
 }
 
 type fakeFS struct {
+	mu    sync.Mutex
 	files map[string]fakeFile
 }
 
@@ -189,6 +197,8 @@ func newFakeFS() *fakeFS {
 }
 
 func (fs *fakeFS) Chown(name string, uid, gid int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
 	fs.files[name] = fakeFile{uid: uid, gid: gid}
 	return nil
 }
@@ -203,3 +213,94 @@ func (fs *fakeFS) Stat(name string) (os.FileInfo, error) {
 	stat.Gid = 666
 	return info, nil
 }
+
+func TestHandleSignals(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHandleSignals", t)
+	defer os.RemoveAll(dir)
+
+	rotated := make(chan string, 2)
+	l := &Rotater{
+		Filename:   logFile(dir),
+		PostRotate: func(path string) { rotated <- path },
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	// fake time is set before signal goroutine starts as race detector does
+	// not see signal delivery as synchronization, backups get distinct names
+	newFakeTime()
+	stop := l.HandleSignals()
+	defer stop()
+
+	for _, sig := range []syscall.Signal{syscall.SIGHUP, syscall.SIGUSR1} {
+		isNil(syscall.Kill(os.Getpid(), sig), t)
+		select {
+		case path := <-rotated:
+			existsWithContent(path, b, t)
+			b = []byte{}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting rotation on %v", sig)
+		}
+	}
+}
+
+func TestHandleSignalsClose(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestHandleSignalsClose", t)
+	defer os.RemoveAll(dir)
+
+	// keep SIGHUP caught after rotater stops handling it
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGHUP)
+	defer signal.Stop(caught)
+
+	rotated := make(chan string, 1)
+	l := &Rotater{
+		Filename:   logFile(dir),
+		PostRotate: func(path string) { rotated <- path },
+	}
+	l.HandleSignals(syscall.SIGHUP)
+	l.Write([]byte("boo!"))
+	newFakeTime()
+	isNil(l.Close(), t)
+
+	isNil(syscall.Kill(os.Getpid(), syscall.SIGHUP), t)
+	select {
+	case <-caught:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for SIGHUP")
+	}
+	select {
+	case path := <-rotated:
+		t.Fatalf("expected no rotation after Close, got %s", path)
+	case <-time.After(100 * time.Millisecond):
+	}
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+	fileCount(dir, 1, t)
+}
+
+func TestDSync(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestDSync", t)
+	defer os.RemoveAll(dir)
+
+	l := &Rotater{
+		Filename: logFile(dir),
+		DSync:    true,
+	}
+	defer l.Close()
+	l.Write([]byte("boo!"))
+
+	fdinfo, err := ioutil.ReadFile(fmt.Sprintf("/proc/self/fdinfo/%d", l.file.Fd()))
+	if err != nil {
+		t.Skipf("can't read fdinfo: %v", err)
+	}
+	var flags int
+	for _, line := range strings.Split(string(fdinfo), "\n") {
+		if strings.HasPrefix(line, "flags:") {
+			fmt.Sscanf(strings.TrimSpace(line[len("flags:"):]), "%o", &flags)
+		}
+	}
+	assert(flags&syscall.O_DSYNC == syscall.O_DSYNC, t, "expected O_DSYNC in flags %o", flags)
+}
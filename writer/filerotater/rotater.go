@@ -11,27 +11,31 @@
 package filerotater
 
 import (
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/writer"
 )
 
 const (
 	backupTimeFormat = "2006-01-02T15-04-05.000"
-	compressSuffix   = ".gz"
+	compressSuffix   = ".gz" // suffix of gzip codec
 	defaultMaxSize   = 100
+	secondsPerDay    = 24 * 60 * 60
+
+	// spaceCheckInterval is how often free disk space is checked on writes
+	// when MinFreeSpace is set.
+	spaceCheckInterval = time.Second
 )
 
-// ensure we always implement io.WriteCloser
-var _ io.WriteCloser = (*Rotater)(nil)
+// ensure we always implement writer.LeveledWriter
+var _ writer.LeveledWriter = (*Rotater)(nil)
 
 // Rotater is an io.WriteCloser that writes to the specified filename.
 //
@@ -55,6 +59,11 @@ var _ io.WriteCloser = (*Rotater)(nil)
 // `/var/log/foo/server.log`, a backup created at 6:30pm on Nov 11 2016 would
 // use the filename `/var/log/foo/server-2016-11-04T18-30-00.000.log`
 //
+// Backup names can be changed with BackupFormat and backups can be moved into
+// another, optionally time based, directory with BackupDir. For example with
+// BackupDir `archive/%Y/%m` and BackupFormat `{name}.%Y%m%d.{seq}{ext}` the
+// backup above would be `/var/log/foo/archive/2016/11/server.20161104.1.log`.
+//
 // Cleaning Up Old Log Files
 //
 // Whenever a new logfile gets created, old log files may be deleted.  The most
@@ -65,6 +74,25 @@ var _ io.WriteCloser = (*Rotater)(nil)
 // time, which may differ from the last time that file was written to.
 //
 // If MaxBackups and MaxAge are both 0, no old log files will be deleted.
+//
+// Time Based Rotation
+//
+// If RotateEvery is set, the log file is also rotated whenever a wall-clock
+// boundary of RotateEvery passes, in local time if LocalTime is set or in UTC
+// otherwise. Durations shorter than a day are aligned to midnight, so an hour
+// rotates at every o'clock and 15 minutes at :00, :15, :30 and :45. Durations
+// of one or more days rotate at midnight, weekly rotations happening on
+// Mondays. A log file left from an earlier period is rotated on first write.
+// Size based rotation still applies between boundaries.
+//
+// Free Space Guard
+//
+// If MinFreeSpace is set, available space on the filesystem of the log file
+// is checked on writes, at most once per second. When it falls below
+// MinFreeSpace, oldest backups are deleted until enough space is free. If
+// DropOnLowSpace is also set, lines less severe than LowSpaceLevel written
+// with WriteIfLevel are dropped while space is still low. Free space can only
+// be checked on linux, the guard is a no-op on other platforms.
 type Rotater struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.  It uses <processname>-nlogrotater.log in
@@ -93,15 +121,104 @@ type Rotater struct {
 	LocalTime bool `json:"localtime" yaml:"localtime"`
 
 	// Compress determines if the rotated log files should be compressed
-	// using gzip. The default is not to perform compression.
+	// using Compression codec. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
 
-	size int64
-	file *os.File
-	mu   sync.Mutex
-
-	millCh    chan bool
-	startMill sync.Once
+	// Compression is name of a registered Codec used to compress rotated log
+	// files, like gzip, zlib or deflate. It defaults to gzip.
+	Compression string `json:"compression" yaml:"compression"`
+
+	// CompressLevel is compression level passed to codec. The default is to
+	// use default level of the codec.
+	CompressLevel int `json:"compresslevel" yaml:"compresslevel"`
+
+	// MaxTotalSize is the maximum total size in megabytes of backup files,
+	// counting compressed files with their compressed size. Oldest backups
+	// are removed when it is exceeded. The default is not to remove old log
+	// files based on total size.
+	MaxTotalSize int `json:"maxtotalsize" yaml:"maxtotalsize"`
+
+	// BackupFormat is the name template of backup files. It may contain
+	// strftime style tokens %Y, %y, %m, %d, %H, %M, %S, %L (milliseconds) and
+	// %%, and placeholders {name} (log filename without extension), {ext}
+	// (extension with leading dot) and {seq} (sequence number starting at 1).
+	// It defaults to DefaultBackupFormat.
+	BackupFormat string `json:"backupformat" yaml:"backupformat"`
+
+	// BackupDir is the directory to move backups into. It may contain the
+	// same time tokens as BackupFormat, like /var/log/app/archive/%Y/%m, and
+	// relative paths are relative to directory of Filename. It must be on the
+	// same filesystem as Filename. The default is directory of Filename.
+	BackupDir string `json:"backupdir" yaml:"backupdir"`
+
+	// RotateEvery is the period of time based rotation, for example time.Hour
+	// for hourly or 24*time.Hour for daily rotation. The default is to rotate
+	// only on MaxSize.
+	RotateEvery time.Duration `json:"rotateevery" yaml:"rotateevery"`
+
+	// PostRotate is called from the mill goroutine with final path of each
+	// backup, after it is compressed if compression is enabled.
+	PostRotate func(path string) `json:"-" yaml:"-"`
+
+	// OnCompressed is called from the mill goroutine when a backup at src is
+	// compressed into dst.
+	OnCompressed func(src, dst string) `json:"-" yaml:"-"`
+
+	// ErrorHandler is called with errors of compression and removal of old log
+	// files. They are printed to stderr if it is not set.
+	ErrorHandler func(err error) `json:"-" yaml:"-"`
+
+	// WatchInterval enables detection of external rotation, like logrotate
+	// moving or truncating the log file. Filename is checked at most once per
+	// WatchInterval on writes and reopened if it no longer refers to the open
+	// file. The default is not to check.
+	WatchInterval time.Duration `json:"watchinterval" yaml:"watchinterval"`
+
+	// SyncEvery makes Rotater fsync the log file after every SyncEvery
+	// writes. The default is to leave flushing to the OS.
+	SyncEvery int `json:"syncevery" yaml:"syncevery"`
+
+	// SyncInterval makes Rotater fsync the log file on first write after
+	// SyncInterval has passed since last fsync.
+	SyncInterval time.Duration `json:"syncinterval" yaml:"syncinterval"`
+
+	// SyncOnLevel makes Rotater fsync the log file after lines written with
+	// WriteIfLevel at SyncLevel or more severe levels, like ERROR and FATAL.
+	SyncOnLevel bool       `json:"synconlevel" yaml:"synconlevel"`
+	SyncLevel   nlog.Level `json:"synclevel" yaml:"synclevel"`
+
+	// DSync opens log files with O_DSYNC (O_SYNC where not available), so
+	// each write returns only after data reaches stable storage.
+	DSync bool `json:"dsync" yaml:"dsync"`
+
+	// MinFreeSpace is the minimum free space in megabytes to keep on the
+	// filesystem of the log file by deleting oldest backups. The default is
+	// not to check free space.
+	MinFreeSpace int `json:"minfreespace" yaml:"minfreespace"`
+
+	// DropOnLowSpace makes Rotater drop lines less severe than LowSpaceLevel
+	// while free space is below MinFreeSpace, like DEBUG and INFO lines with
+	// LowSpaceLevel WARNING. Lines written with Write are never dropped.
+	// LowSpaceLevel defaults to WARNING when it is not set, as FATAL.
+	DropOnLowSpace bool       `json:"droponlowspace" yaml:"droponlowspace"`
+	LowSpaceLevel  nlog.Level `json:"lowspacelevel" yaml:"lowspacelevel"`
+
+	writes    int
+	lastSync  time.Time
+	size      int64
+	file      *os.File
+	mu        sync.Mutex
+	rotateAt  time.Time
+	nextCheck time.Time
+	hooks     hooks
+
+	lowSpace       bool
+	nextSpaceCheck time.Time
+
+	millCh   chan bool
+	millDone chan struct{}
+
+	stopSignals []func()
 }
 
 var (
@@ -111,6 +228,9 @@ var (
 	// os_Stat exists so it can be mocked out by tests.
 	os_Stat = os.Stat
 
+	// disk_Free exists so it can be mocked out by tests.
+	disk_Free = diskFree
+
 	// megabyte is the conversion factor between MaxSize and bytes.  It is a
 	// variable so tests can mock it out and not need to write megabytes of data
 	// to disk.
@@ -118,13 +238,77 @@ var (
 )
 
 // Write implements io.Writer.  If a write would cause the log file to be larger
-// than MaxSize, the file is closed, renamed to include a timestamp of the
-// current time, and a new log file is created using the original log file name.
+// than MaxSize or a RotateEvery boundary has passed, the file is closed, renamed
+// to include a timestamp of the current time, and a new log file is created
+// using the original log file name.  If WatchInterval is set and the log file
+// was moved or removed externally, Filename is reopened before writing.
 // If the length of the write is greater than MaxSize, an error is returned.
 func (l *Rotater) Write(p []byte) (n int, err error) {
+	return l.write(p, nlog.FATAL, false)
+}
+
+// WriteIfLevel implements writer.LeveledWriter. It writes p like Write and
+// fsyncs the log file if SyncOnLevel is set and lvl is SyncLevel or more
+// severe. If DropOnLowSpace is set and free space is low, p is dropped if lvl
+// is less severe than LowSpaceLevel.
+func (l *Rotater) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	return l.write(p, lvl, true)
+}
+
+// GetLevel implements writer.LeveledWriter. Rotater writes all levels,
+// filtering is left to wrapping writers.
+func (l *Rotater) GetLevel() nlog.Level {
+	return nlog.DEBUG
+}
+
+// Enabled implements writer.LeveledWriter. Rotater writes all levels.
+func (l *Rotater) Enabled(lvl nlog.Level) bool {
+	return true
+}
+
+// Sync commits the current log file to stable storage.
+func (l *Rotater) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sync()
+}
+
+// sync fsyncs the file if it is open.
+func (l *Rotater) sync() error {
+	l.writes = 0
+	l.lastSync = currentTime()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// syncDue reports whether durability options require an fsync after a write.
+func (l *Rotater) syncDue() bool {
+	if l.SyncEvery > 0 && l.writes >= l.SyncEvery {
+		return true
+	}
+	return l.SyncInterval > 0 && !currentTime().Before(l.lastSync.Add(l.SyncInterval))
+}
+
+// syncing reports whether any fsync option is set.
+func (l *Rotater) syncing() bool {
+	return l.SyncEvery > 0 || l.SyncInterval > 0 || l.SyncOnLevel
+}
+
+// write writes p of level lvl, rotating if necessary, and fsyncs if required
+// by level or due. Level is only used if leveled is set.
+func (l *Rotater) write(p []byte, lvl nlog.Level, leveled bool) (n int, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if l.MinFreeSpace > 0 {
+		l.checkSpace()
+		if l.lowSpace && l.DropOnLowSpace && leveled && lvl > l.lowSpaceLevel() {
+			return len(p), nil
+		}
+	}
+
 	writeLen := int64(len(p))
 	if writeLen > l.max() {
 		return 0, fmt.Errorf(
@@ -132,13 +316,17 @@ func (l *Rotater) Write(p []byte) (n int, err error) {
 		)
 	}
 
+	if l.file != nil && l.WatchInterval > 0 {
+		l.checkExternal()
+	}
+
 	if l.file == nil {
 		if err = l.openExistingOrNew(len(p)); err != nil {
 			return 0, err
 		}
 	}
 
-	if l.size+writeLen > l.max() {
+	if l.size+writeLen > l.max() || l.rotationDue() {
 		if err := l.rotate(); err != nil {
 			return 0, err
 		}
@@ -146,15 +334,46 @@ func (l *Rotater) Write(p []byte) (n int, err error) {
 
 	n, err = l.file.Write(p)
 	l.size += int64(n)
+	l.writes++
+	forceSync := leveled && l.SyncOnLevel && lvl <= l.SyncLevel
+	if err == nil && (forceSync || l.syncDue()) {
+		err = l.sync()
+	}
 
 	return n, err
 }
 
-// Close implements io.Closer, and closes the current logfile.
+// Describe returns info of rotater with its file name, see writer.Describe
+func (l *Rotater) Describe() writer.Info {
+	return writer.Info{Type: "filerotator", Name: l.Filename}
+}
+
+// Close implements io.Closer, and closes the current logfile. The file is
+// fsynced first if any of fsync options is set. Close stops handling signals
+// started by HandleSignals, waits for compression and removal of old log files
+// in progress and stops the mill goroutine, which is started again by further
+// writes.
 func (l *Rotater) Close() error {
 	l.mu.Lock()
-	defer l.mu.Unlock()
-	return l.close()
+	stopSignals := l.stopSignals
+	l.stopSignals = nil
+	l.mu.Unlock()
+	// signal goroutines rotate with mu held, so they are stopped before it is
+	// taken to close the file
+	for _, stop := range stopSignals {
+		stop()
+	}
+
+	l.mu.Lock()
+	err := l.close()
+	millCh, millDone := l.millCh, l.millDone
+	l.millCh, l.millDone = nil, nil
+	l.mu.Unlock()
+	if millCh != nil {
+		close(millCh)
+		<-millDone
+	}
+	return err
 }
 
 // close closes the file if it is open.
@@ -162,7 +381,13 @@ func (l *Rotater) close() error {
 	if l.file == nil {
 		return nil
 	}
-	err := l.file.Close()
+	var err error
+	if l.syncing() {
+		err = l.file.Sync()
+	}
+	if errClose := l.file.Close(); err == nil {
+		err = errClose
+	}
 	l.file = nil
 	return err
 }
@@ -207,10 +432,18 @@ func (l *Rotater) openNew() error {
 		// Copy the mode off the old logfile.
 		mode = info.Mode()
 		// move the existing file
-		newname := backupName(name, l.LocalTime)
+		layout, err := l.layout()
+		if err != nil {
+			return err
+		}
+		newname := layout.backupName(currentTime())
+		if err := os.MkdirAll(filepath.Dir(newname), 0755); err != nil {
+			return fmt.Errorf("can't make directories for backup: %s", err)
+		}
 		if err := os.Rename(name, newname); err != nil {
 			return fmt.Errorf("can't rename log file: %s", err)
 		}
+		l.addRotated(newname)
 
 		// this is a no-op anywhere but linux
 		if err := chown(name, info); err != nil {
@@ -221,32 +454,16 @@ func (l *Rotater) openNew() error {
 	// we use truncate here because this should only get called when we've moved
 	// the file ourselves. if someone else creates the file in the meantime,
 	// just wipe out the contents.
-	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY|os.O_TRUNC, mode)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY|os.O_TRUNC|l.syncFlag(), mode)
 	if err != nil {
 		return fmt.Errorf("can't open new logfile: %s", err)
 	}
 	l.file = f
 	l.size = 0
+	l.scheduleRotation()
 	return nil
 }
 
-// backupName creates a new filename from the given name, inserting a timestamp
-// between the filename and the extension, using the local time if requested
-// (otherwise UTC).
-func backupName(name string, local bool) string {
-	dir := filepath.Dir(name)
-	filename := filepath.Base(name)
-	ext := filepath.Ext(filename)
-	prefix := filename[:len(filename)-len(ext)]
-	t := currentTime()
-	if !local {
-		t = t.UTC()
-	}
-
-	timestamp := t.Format(backupTimeFormat)
-	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, timestamp, ext))
-}
-
 // openExistingOrNew opens the logfile if it exists and if the current write
 // would not put it over MaxSize.  If there is no such file or the write would
 // put it over the MaxSize, a new file is created.
@@ -265,8 +482,12 @@ func (l *Rotater) openExistingOrNew(writeLen int) error {
 	if info.Size()+int64(writeLen) >= l.max() {
 		return l.rotate()
 	}
+	if l.RotateEvery > 0 && !currentTime().Before(l.nextRotation(info.ModTime())) {
+		// file was last written in an earlier period
+		return l.rotate()
+	}
 
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|l.syncFlag(), 0644)
 	if err != nil {
 		// if we fail to open the old log file for some reason, just ignore
 		// it and open a new log file.
@@ -274,9 +495,122 @@ func (l *Rotater) openExistingOrNew(writeLen int) error {
 	}
 	l.file = file
 	l.size = info.Size()
+	l.scheduleRotation()
 	return nil
 }
 
+// checkExternal closes the log file if Filename was moved or removed since it
+// was opened so it is reopened by Write, and resets size if it was truncated.
+func (l *Rotater) checkExternal() {
+	now := currentTime()
+	if now.Before(l.nextCheck) {
+		return
+	}
+	l.nextCheck = now.Add(l.WatchInterval)
+	current, err := l.file.Stat()
+	if err != nil {
+		return
+	}
+	info, err := os_Stat(l.filename())
+	if err != nil || !os.SameFile(info, current) {
+		l.handleError(l.close())
+		return
+	}
+	if current.Size() < l.size {
+		// truncated by copytruncate
+		l.size = current.Size()
+	}
+}
+
+// checkSpace updates low space state at most once per spaceCheckInterval,
+// starting the mill to delete old backups if space is low.
+func (l *Rotater) checkSpace() {
+	now := currentTime()
+	if now.Before(l.nextSpaceCheck) {
+		return
+	}
+	l.nextSpaceCheck = now.Add(spaceCheckInterval)
+	free, err := disk_Free(l.dir())
+	if err != nil {
+		l.lowSpace = false
+		return
+	}
+	l.lowSpace = free < l.minFree()
+	if l.lowSpace {
+		l.mill()
+	}
+}
+
+// lowSpaceLevel returns LowSpaceLevel, WARNING if it is not set
+func (l *Rotater) lowSpaceLevel() nlog.Level {
+	if l.LowSpaceLevel == nlog.FATAL {
+		return nlog.WARNING
+	}
+	return l.LowSpaceLevel
+}
+
+// minFree returns MinFreeSpace in bytes.
+func (l *Rotater) minFree() uint64 {
+	return uint64(l.MinFreeSpace) * uint64(megabyte)
+}
+
+// errDiskFreeUnsupported is returned by diskFree on platforms where free
+// space can't be checked.
+var errDiskFreeUnsupported = errors.New("free disk space is not supported on this platform")
+
+// scheduleRotation sets times of next time based rotation and external
+// rotation check for a newly opened log file.
+func (l *Rotater) scheduleRotation() {
+	now := currentTime()
+	if l.RotateEvery > 0 {
+		l.rotateAt = l.nextRotation(now)
+	}
+	l.nextCheck = now.Add(l.WatchInterval)
+	l.lastSync = now
+	l.writes = 0
+}
+
+// syncFlag returns flag used for opening log files.
+func (l *Rotater) syncFlag() int {
+	if l.DSync {
+		return oDSync
+	}
+	return 0
+}
+
+// rotationDue reports whether a time based rotation is due.
+func (l *Rotater) rotationDue() bool {
+	return l.RotateEvery > 0 && !currentTime().Before(l.rotateAt)
+}
+
+// nextRotation returns the first RotateEvery boundary after t, in local time
+// if requested (otherwise UTC).
+func (l *Rotater) nextRotation(t time.Time) time.Time {
+	if !l.LocalTime {
+		t = t.UTC()
+	}
+	y, m, d := t.Date()
+	if l.RotateEvery < 24*time.Hour {
+		every := int(l.RotateEvery / time.Second)
+		if every < 1 {
+			every = 1
+		}
+		h, min, sec := t.Clock()
+		next := ((h*3600+min*60+sec)/every + 1) * every
+		if next > secondsPerDay {
+			// periods not dividing a day restart at midnight
+			next = secondsPerDay
+		}
+		return time.Date(y, m, d, 0, 0, next, 0, t.Location())
+	}
+	days := int64(l.RotateEvery / (24 * time.Hour))
+	// days are counted from a Monday, 1970-01-05, so weekly rotation happens
+	// on Mondays
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()/secondsPerDay + 3
+	next := (day/days + 1) * days
+	return time.Date(y, m, d+int(next-day), 0, 0, 0, 0, t.Location())
+}
+
 // filename generates the name of the logfile from the current time.
 func (l *Rotater) filename() string {
 	if l.Filename != "" {
@@ -291,7 +625,7 @@ func (l *Rotater) filename() string {
 // files are removed, keeping at most l.MaxBackups files, as long as
 // none of them are older than MaxAge.
 func (l *Rotater) millRunOnce() error {
-	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
+	if l.MaxBackups == 0 && l.MaxAge == 0 && l.MaxTotalSize == 0 && l.MinFreeSpace == 0 && !l.Compress {
 		return nil
 	}
 
@@ -308,10 +642,7 @@ func (l *Rotater) millRunOnce() error {
 		for _, f := range files {
 			// Only count the uncompressed log file or the
 			// compressed log file, not both.
-			fn := f.Name()
-			if strings.HasSuffix(fn, compressSuffix) {
-				fn = fn[:len(fn)-len(compressSuffix)]
-			}
+			fn := f.path[:len(f.path)-len(f.suffix)]
 			preserved[fn] = true
 
 			if len(preserved) > l.MaxBackups {
@@ -337,97 +668,141 @@ func (l *Rotater) millRunOnce() error {
 		files = remaining
 	}
 
+	var codec Codec
 	if l.Compress {
+		if codec, err = LookupCodec(l.Compression); err != nil {
+			return err
+		}
 		for _, f := range files {
-			if !strings.HasSuffix(f.Name(), compressSuffix) {
+			if f.suffix == "" {
 				compress = append(compress, f)
 			}
 		}
 	}
 
 	for _, f := range remove {
-		errRemove := os.Remove(filepath.Join(l.dir(), f.Name()))
+		errRemove := os.Remove(f.path)
 		if err == nil && errRemove != nil {
 			err = errRemove
 		}
+		if errRemove == nil {
+			l.emit(Removed, f.path, "")
+		}
 	}
 	for _, f := range compress {
-		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix)
+		fn := f.path
+		errCompress := compressLogFile(fn, fn+codec.Suffix(), codec, l.CompressLevel)
 		if err == nil && errCompress != nil {
 			err = errCompress
 		}
+		if errCompress == nil {
+			l.compressed(fn, fn+codec.Suffix())
+		}
+	}
+
+	if l.MaxTotalSize > 0 {
+		// list again to count sizes after compression
+		errTotal := l.removeOverTotalSize()
+		if err == nil && errTotal != nil {
+			err = errTotal
+		}
 	}
+	if l.MinFreeSpace > 0 {
+		errFree := l.removeForFreeSpace()
+		if err == nil && errFree != nil {
+			err = errFree
+		}
+	}
+
+	return err
+}
 
+// removeOverTotalSize removes oldest backups exceeding MaxTotalSize.
+func (l *Rotater) removeOverTotalSize() error {
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	limit := int64(l.MaxTotalSize) * int64(megabyte)
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+		if total > limit {
+			errRemove := os.Remove(f.path)
+			if err == nil && errRemove != nil {
+				err = errRemove
+			}
+			if errRemove == nil {
+				l.emit(Removed, f.path, "")
+			}
+		}
+	}
 	return err
 }
 
+// removeForFreeSpace removes oldest backups until MinFreeSpace is available
+// on the filesystem of the log file.
+func (l *Rotater) removeForFreeSpace() error {
+	free, err := disk_Free(l.dir())
+	if err == errDiskFreeUnsupported {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't get free disk space: %s", err)
+	}
+	if free >= l.minFree() {
+		return nil
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+	for i := len(files) - 1; i >= 0 && free < l.minFree(); i-- {
+		f := files[i]
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+		l.emit(Removed, f.path, "")
+		if free, err = disk_Free(l.dir()); err != nil {
+			return fmt.Errorf("can't get free disk space: %s", err)
+		}
+	}
+	return nil
+}
+
 // millRun runs in a goroutine to manage post-rotation compression and removal
-// of old log files.
-func (l *Rotater) millRun() {
-	for _ = range l.millCh {
-		// what am I going to do, log this?
-		_ = l.millRunOnce()
+// of old log files, and to report rotated backups.
+// It returns when millCh is closed by Close, closing done.
+func (l *Rotater) millRun(millCh <-chan bool, done chan<- struct{}) {
+	defer close(done)
+	for _ = range millCh {
+		l.handleError(l.millRunOnce())
+		l.postRotate()
 	}
 }
 
 // mill performs post-rotation compression and removal of stale log files,
-// starting the mill goroutine if necessary.
+// starting the mill goroutine if necessary. It is called with mu held.
 func (l *Rotater) mill() {
-	l.startMill.Do(func() {
+	if l.millCh == nil {
 		l.millCh = make(chan bool, 1)
-		go l.millRun()
-	})
+		l.millDone = make(chan struct{})
+		go l.millRun(l.millCh, l.millDone)
+	}
 	select {
 	case l.millCh <- true:
 	default:
 	}
 }
 
-// oldLogFiles returns the list of backup log files stored in the same
-// directory as the current log file, sorted by ModTime
+// oldLogFiles returns the list of backup log files matching backup layout,
+// sorted by the time encoded in their names, newest first.
 func (l *Rotater) oldLogFiles() ([]logInfo, error) {
-	files, err := ioutil.ReadDir(l.dir())
+	layout, err := l.layout()
 	if err != nil {
-		return nil, fmt.Errorf("can't read log file directory: %s", err)
+		return nil, err
 	}
-	logFiles := []logInfo{}
-
-	prefix, ext := l.prefixAndExt()
-
-	for _, f := range files {
-		if f.IsDir() {
-			continue
-		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
-		}
-		if t, err := l.timeFromName(f.Name(), prefix, ext+compressSuffix); err == nil {
-			logFiles = append(logFiles, logInfo{t, f})
-			continue
-		}
-		// error parsing means that the suffix at the end was not generated
-		// by filerotater, and therefore it's not a backup file.
-	}
-
-	sort.Sort(byFormatTime(logFiles))
-
-	return logFiles, nil
-}
-
-// timeFromName extracts the formatted time from the filename by stripping off
-// the filename's prefix and extension. This prevents someone's filename from
-// confusing time.parse.
-func (l *Rotater) timeFromName(filename, prefix, ext string) (time.Time, error) {
-	if !strings.HasPrefix(filename, prefix) {
-		return time.Time{}, errors.New("mismatched prefix")
-	}
-	if !strings.HasSuffix(filename, ext) {
-		return time.Time{}, errors.New("mismatched extension")
-	}
-	ts := filename[len(prefix) : len(filename)-len(ext)]
-	return time.Parse(backupTimeFormat, ts)
+	return layout.find()
 }
 
 // max returns the maximum size in bytes of log files before rolling.
@@ -443,18 +818,17 @@ func (l *Rotater) dir() string {
 	return filepath.Dir(l.filename())
 }
 
-// prefixAndExt returns the filename part and extension part from the Rotater's
-// filename.
-func (l *Rotater) prefixAndExt() (prefix, ext string) {
+// nameAndExt returns the filename part without extension and extension part
+// from the Rotater's filename.
+func (l *Rotater) nameAndExt() (name, ext string) {
 	filename := filepath.Base(l.filename())
 	ext = filepath.Ext(filename)
-	prefix = filename[:len(filename)-len(ext)] + "-"
-	return prefix, ext
+	return filename[:len(filename)-len(ext)], ext
 }
 
-// compressLogFile compresses the given log file, removing the
+// compressLogFile compresses the given log file with codec, removing the
 // uncompressed log file if successful.
-func compressLogFile(src, dst string) (err error) {
+func compressLogFile(src, dst string, codec Codec, level int) (err error) {
 	f, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %v", err)
@@ -478,8 +852,6 @@ func compressLogFile(src, dst string) (err error) {
 	}
 	defer gzf.Close()
 
-	gz := gzip.NewWriter(gzf)
-
 	defer func() {
 		if err != nil {
 			os.Remove(dst)
@@ -487,6 +859,10 @@ func compressLogFile(src, dst string) (err error) {
 		}
 	}()
 
+	gz, err := codec.NewWriter(gzf, level)
+	if err != nil {
+		return err
+	}
 	if _, err := io.Copy(gz, f); err != nil {
 		return err
 	}
@@ -507,10 +883,14 @@ func compressLogFile(src, dst string) (err error) {
 	return nil
 }
 
-// logInfo is a convenience struct to return the filename and its embedded
-// timestamp.
+// logInfo is a convenience struct to return the backup path and its embedded
+// timestamp, sequence number and compression suffix.
 type logInfo struct {
 	timestamp time.Time
+	seq       int
+	path      string
+	// suffix is codec suffix of compressed backups
+	suffix string
 	os.FileInfo
 }
 
@@ -518,6 +898,9 @@ type logInfo struct {
 type byFormatTime []logInfo
 
 func (b byFormatTime) Less(i, j int) bool {
+	if b[i].timestamp.Equal(b[j].timestamp) {
+		return b[i].seq > b[j].seq
+	}
 	return b[i].timestamp.After(b[j].timestamp)
 }
 
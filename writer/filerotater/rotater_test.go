@@ -3,13 +3,20 @@ package filerotater
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/writer"
 )
 
 // !!!NOTE!!!
@@ -21,12 +28,25 @@ import (
 // Since all the tests uses the time to determine filenames etc, we need to
 // control the wall clock as much as possible, which means having a wall clock
 // that doesn't change unless we want it to.
-var fakeCurrentTime = time.Now()
+// It is guarded by fakeTimeMu as it is read by mill goroutine.
+var (
+	fakeCurrentTime = time.Now()
+	fakeTimeMu      sync.Mutex
+)
 
 func fakeTime() time.Time {
+	fakeTimeMu.Lock()
+	defer fakeTimeMu.Unlock()
 	return fakeCurrentTime
 }
 
+// setFakeTime sets the fake "current time".
+func setFakeTime(t time.Time) {
+	fakeTimeMu.Lock()
+	fakeCurrentTime = t
+	fakeTimeMu.Unlock()
+}
+
 func TestNewFile(t *testing.T) {
 	currentTime = fakeTime
 
@@ -483,7 +503,8 @@ func TestOldLogFiles(t *testing.T) {
 
 func TestTimeFromName(t *testing.T) {
 	l := &Rotater{Filename: "/var/log/myfoo/foo.log"}
-	prefix, ext := l.prefixAndExt()
+	layout, err := l.layout()
+	isNil(err, t)
 
 	tests := []struct {
 		filename string
@@ -497,7 +518,7 @@ func TestTimeFromName(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		got, err := l.timeFromName(test.filename, prefix, ext)
+		got, _, err := layout.parse(filepath.Join(l.dir(), test.filename))
 		equals(got, test.want, t)
 		equals(err != nil, test.wantErr, t)
 	}
@@ -685,6 +706,653 @@ func TestCompressOnResume(t *testing.T) {
 	fileCount(dir, 2, t)
 }
 
+func TestRotateEvery(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 18, 10, 59, 0, 0, time.UTC))
+	dir := makeTempDir("TestRotateEvery", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:    filename,
+		RotateEvery: time.Hour,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+
+	// still in the same hour, no rotation
+	setFakeTime(fakeTime().Add(59 * time.Second))
+	n, err = l.Write(b)
+	isNil(err, t)
+	existsWithContent(filename, append(b, b...), t)
+	fileCount(dir, 1, t)
+
+	// boundary passed, file rotates before write
+	setFakeTime(fakeTime().Add(time.Second))
+	b2 := []byte("foooooo!")
+	n, err = l.Write(b2)
+	isNil(err, t)
+	equals(len(b2), n, t)
+	existsWithContent(backupFile(dir), append(b, b...), t)
+	existsWithContent(filename, b2, t)
+	fileCount(dir, 2, t)
+}
+
+func TestRotateEveryWithMaxSize(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC))
+	megabyte = 1
+	dir := makeTempDir("TestRotateEveryWithMaxSize", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:    filename,
+		MaxSize:     10,
+		RotateEvery: 24 * time.Hour,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	setFakeTime(fakeTime().Add(time.Minute))
+	b2 := []byte("foooooo!")
+	l.Write(b2)
+	// size limit rotates between boundaries
+	existsWithContent(backupFile(dir), b, t)
+	existsWithContent(filename, b2, t)
+	fileCount(dir, 2, t)
+}
+
+func TestRotateEveryStaleFile(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 18, 0, 30, 0, 0, time.UTC))
+	dir := makeTempDir("TestRotateEveryStaleFile", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	data := []byte("foo!")
+	isNil(ioutil.WriteFile(filename, data, 0644), t)
+	yesterday := fakeTime().Add(-time.Hour)
+	isNil(os.Chtimes(filename, yesterday, yesterday), t)
+
+	l := &Rotater{
+		Filename:    filename,
+		RotateEvery: 24 * time.Hour,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	existsWithContent(backupFile(dir), data, t)
+	existsWithContent(filename, b, t)
+	fileCount(dir, 2, t)
+}
+
+func TestNextRotation(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	tests := []struct {
+		every time.Duration
+		local bool
+		now   time.Time
+		want  time.Time
+	}{
+		{time.Hour, false, time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{15 * time.Minute, false, time.Date(2026, 10, 18, 10, 44, 59, 0, time.UTC), time.Date(2026, 10, 18, 10, 45, 0, 0, time.UTC)},
+		{7 * time.Hour, false, time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, false, time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, true, time.Date(2026, 10, 18, 22, 0, 0, 0, loc), time.Date(2026, 10, 19, 0, 0, 0, 0, loc)},
+		{24 * time.Hour, false, time.Date(2026, 10, 18, 22, 0, 0, 0, loc), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		// 2026-10-18 is a Sunday
+		{7 * 24 * time.Hour, false, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{7 * 24 * time.Hour, false, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		l := &Rotater{RotateEvery: tt.every, LocalTime: tt.local}
+		got := l.nextRotation(tt.now)
+		assert(got.Equal(tt.want), t, "every %v from %v: want %v, got %v", tt.every, tt.now, tt.want, got)
+	}
+}
+
+func TestBackupFormat(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC))
+	megabyte = 1
+	dir := makeTempDir("TestBackupFormat", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:     filename,
+		MaxSize:      10,
+		BackupFormat: "{name}.%Y%m%d.{seq}{ext}",
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	isNil(l.Rotate(), t)
+	isNil(l.Rotate(), t)
+	existsWithContent(filepath.Join(dir, "foobar.20261018.1.log"), b, t)
+	existsWithContent(filepath.Join(dir, "foobar.20261018.2.log"), []byte{}, t)
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(2, len(files), t)
+	// same day, higher sequence is newer
+	equals(filepath.Join(dir, "foobar.20261018.2.log"), files[0].path, t)
+	equals(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), files[0].timestamp, t)
+}
+
+func TestBackupNameCollision(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC))
+	dir := makeTempDir("TestBackupNameCollision", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:     filename,
+		BackupFormat: "{name}-%Y%m%d{ext}",
+		MaxBackups:   2,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	isNil(l.Rotate(), t)
+	b2 := []byte("foooooo!")
+	l.Write(b2)
+	isNil(l.Rotate(), t)
+
+	<-time.After(10 * time.Millisecond)
+
+	// second backup of the day gets a sequence instead of overwriting
+	existsWithContent(filepath.Join(dir, "foobar-20261018.log"), b, t)
+	existsWithContent(filepath.Join(dir, "foobar-20261018.log.1"), b2, t)
+	fileCount(dir, 3, t)
+}
+
+func TestBackupDir(t *testing.T) {
+	currentTime = fakeTime
+	setFakeTime(time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC))
+	megabyte = 1
+	dir := makeTempDir("TestBackupDir", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:   filename,
+		MaxSize:    10,
+		BackupDir:  "archive/%Y/%m",
+		MaxBackups: 1,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	isNil(l.Rotate(), t)
+	first := filepath.Join(dir, "archive", "2026", "10", "foobar-2026-10-18T10-30-00.000.log")
+	existsWithContent(first, b, t)
+	l.Write(b)
+
+	setFakeTime(time.Date(2026, 11, 2, 8, 0, 0, 0, time.UTC))
+	b2 := []byte("foooooo!")
+	l.Write(b2)
+	second := filepath.Join(dir, "archive", "2026", "11", "foobar-2026-11-02T08-00-00.000.log")
+	existsWithContent(second, b, t)
+
+	<-time.After(10 * time.Millisecond)
+
+	// backups in other directories of the layout are found and removed
+	notExist(first, t)
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(1, len(files), t)
+	equals(second, files[0].path, t)
+}
+
+func TestBackupLayoutExpand(t *testing.T) {
+	l := &Rotater{
+		Filename:     "/var/log/app/server.log",
+		BackupDir:    "/var/log/app/archive/%Y/%m/",
+		BackupFormat: "{name}_%y%m%d-%H%M%S.%L_%%{ext}",
+	}
+	layout, err := l.layout()
+	isNil(err, t)
+	equals("/var/log/app/archive", layout.root, t)
+	equals(2, layout.depth, t)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	name := filepath.Join(layout.expand(layout.dir, at, 0), layout.expand(layout.format, at, 1))
+	equals(filepath.FromSlash("/var/log/app/archive/2026/01/server_260102-030405.006_%.log"), name, t)
+	got, _, err := layout.parse(name)
+	isNil(err, t)
+	equals(at, got, t)
+
+	_, _, err = layout.parse(filepath.FromSlash("/var/log/app/archive/2026/01/server_260102-030405.006_%.log.gz"))
+	isNil(err, t)
+	_, _, err = layout.parse(filepath.FromSlash("/var/log/app/archive/2026/server_260102-030405.006_%.log"))
+	notNil(err, t)
+	_, _, err = layout.parse(filepath.FromSlash("/var/log/app/archive/2026/13/server_261302-030405.006_%.log"))
+	notNil(err, t)
+
+	l.BackupFormat = "archive/{name}"
+	_, err = l.layout()
+	notNil(err, t)
+}
+
+func TestCompressCodecLevel(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestCompressCodecLevel", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := NewFileRotater(
+		WithFilename(filename),
+		WithMaxSize(10),
+		WithUTC(),
+		WithCompression("zlib"),
+		WithCompressLevel(zlib.BestCompression),
+	)
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	// we need to wait a little bit since the files get compressed on a different
+	// goroutine.
+	<-time.After(300 * time.Millisecond)
+
+	bc := new(bytes.Buffer)
+	zw, err := zlib.NewWriterLevel(bc, zlib.BestCompression)
+	isNil(err, t)
+	zw.Write(b)
+	isNil(zw.Close(), t)
+	existsWithContent(backupFile(dir)+".zz", bc.Bytes(), t)
+	notExist(backupFile(dir), t)
+	fileCount(dir, 2, t)
+}
+
+// upperCodec is a test codec upper casing data.
+type upperCodec struct{ w io.Writer }
+
+func (c upperCodec) Write(p []byte) (int, error) { return c.w.Write(bytes.ToUpper(p)) }
+func (c upperCodec) Close() error                { return nil }
+
+func TestRegisterCodec(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	RegisterCodec(NewCodec("upper", ".up", func(w io.Writer, level int) (io.WriteCloser, error) {
+		return upperCodec{w}, nil
+	}))
+	_, err := LookupCodec("nope")
+	notNil(err, t)
+
+	dir := makeTempDir("TestRegisterCodec", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Compress:    true,
+		Compression: "upper",
+		Filename:    filename,
+		MaxSize:     10,
+		MaxBackups:  1,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	newFakeTime()
+	first := backupFile(dir)
+	isNil(l.Rotate(), t)
+	<-time.After(300 * time.Millisecond)
+	existsWithContent(first+".up", []byte("BOO!"), t)
+
+	// compressed backups of registered codecs are found for retention
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	<-time.After(300 * time.Millisecond)
+	notExist(first+".up", t)
+	existsWithContent(backupFile(dir)+".up", []byte{}, t)
+	fileCount(dir, 2, t)
+}
+
+func TestMaxTotalSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMaxTotalSize", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := NewFileRotater(WithFilename(filename), WithMaxSize(10), WithMaxTotalSize(20), WithUTC())
+	defer l.Close()
+	var backups []string
+	for _, b := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc"} {
+		l.Write([]byte(b))
+		newFakeTime()
+		backups = append(backups, backupFile(dir))
+		isNil(l.Rotate(), t)
+	}
+	<-time.After(10 * time.Millisecond)
+
+	// 3 backups of 8 bytes exceed 20 bytes, oldest one is removed
+	notExist(backups[0], t)
+	existsWithContent(backups[1], []byte("bbbbbbbb"), t)
+	existsWithContent(backups[2], []byte("cccccccc"), t)
+	fileCount(dir, 3, t)
+}
+
+func TestPostRotateHooks(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestPostRotateHooks", t)
+	defer os.RemoveAll(dir)
+
+	rotated := make(chan string, 1)
+	compressed := make(chan [2]string, 1)
+	filename := logFile(dir)
+	l := NewFileRotater(
+		WithFilename(filename),
+		WithMaxSize(10),
+		WithCompress(),
+		WithPostRotate(func(path string) { rotated <- path }),
+		WithOnCompressed(func(src, dst string) { compressed <- [2]string{src, dst} }),
+	)
+	defer l.Close()
+	events := l.Events()
+	l.Write([]byte("boo!"))
+	newFakeTime()
+	backup := filepath.Join(dir, "foobar-"+fakeTime().Format(backupTimeFormat)+".log")
+	isNil(l.Rotate(), t)
+
+	select {
+	case got := <-compressed:
+		equals([2]string{backup, backup + compressSuffix}, got, t)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for OnCompressed")
+	}
+	select {
+	case got := <-rotated:
+		equals(backup+compressSuffix, got, t)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for PostRotate")
+	}
+	var got []Event
+	for len(got) < 2 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for events, got %v", got)
+		}
+	}
+	equals(Compressed, got[0].Type, t)
+	equals(backup, got[0].Source, t)
+	equals(Rotated, got[1].Type, t)
+	equals(backup+compressSuffix, got[1].Path, t)
+}
+
+func TestErrorHandler(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestErrorHandler", t)
+	defer os.RemoveAll(dir)
+
+	errs := make(chan error, 1)
+	l := &Rotater{
+		Filename:     logFile(dir),
+		MaxSize:      10,
+		Compress:     true,
+		Compression:  "nope",
+		ErrorHandler: func(err error) { errs <- err },
+	}
+	defer l.Close()
+	l.Write([]byte("boo!"))
+	select {
+	case err := <-errs:
+		notNil(err, t)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+}
+
+func TestWatchMoved(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestWatchMoved", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:      filename,
+		WatchInterval: time.Second,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+
+	// moved by an external tool
+	moved := filepath.Join(dir, "foobar.log.1")
+	isNil(os.Rename(filename, moved), t)
+
+	// not checked again within interval, still writing to moved file
+	l.Write(b)
+	existsWithContent(moved, append(b, b...), t)
+
+	setFakeTime(fakeTime().Add(time.Second))
+	b2 := []byte("foooooo!")
+	l.Write(b2)
+	existsWithContent(moved, append(b, b...), t)
+	existsWithContent(filename, b2, t)
+	fileCount(dir, 2, t)
+}
+
+func TestWatchTruncated(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestWatchTruncated", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Rotater{
+		Filename:      filename,
+		MaxSize:       10,
+		WatchInterval: time.Second,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	l.Write(b)
+
+	// copytruncate, size must be reset so the write does not rotate
+	isNil(os.Truncate(filename, 0), t)
+	setFakeTime(fakeTime().Add(time.Second))
+	l.Write(b)
+	existsWithContent(filename, b, t)
+	fileCount(dir, 1, t)
+}
+
+func TestSyncEvery(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestSyncEvery", t)
+	defer os.RemoveAll(dir)
+
+	l := &Rotater{
+		Filename:  logFile(dir),
+		SyncEvery: 2,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	equals(1, l.writes, t)
+	l.Write(b)
+	// synced after second write
+	equals(0, l.writes, t)
+	l.Write(b)
+	equals(1, l.writes, t)
+	isNil(l.Sync(), t)
+	equals(0, l.writes, t)
+	existsWithContent(logFile(dir), bytes.Repeat(b, 3), t)
+}
+
+func TestSyncInterval(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestSyncInterval", t)
+	defer os.RemoveAll(dir)
+
+	l := &Rotater{
+		Filename:     logFile(dir),
+		SyncInterval: time.Second,
+	}
+	defer l.Close()
+	b := []byte("boo!")
+	l.Write(b)
+	l.Write(b)
+	equals(2, l.writes, t)
+	setFakeTime(fakeTime().Add(time.Second))
+	l.Write(b)
+	equals(0, l.writes, t)
+	equals(fakeTime(), l.lastSync, t)
+}
+
+func TestSyncOnLevel(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestSyncOnLevel", t)
+	defer os.RemoveAll(dir)
+
+	l := NewFileRotater(WithFilename(logFile(dir)), WithSyncLevel(nlog.ERROR))
+	defer l.Close()
+	b := []byte("boo!")
+	l.WriteIfLevel(nlog.INFO, b)
+	l.WriteIfLevel(nlog.WARNING, b)
+	equals(2, l.writes, t)
+	l.WriteIfLevel(nlog.ERROR, b)
+	equals(0, l.writes, t)
+	// plain writes have no level
+	l.Write(b)
+	equals(1, l.writes, t)
+	existsWithContent(logFile(dir), bytes.Repeat(b, 4), t)
+}
+
+func TestSyncOnLevelParallel(t *testing.T) {
+	currentTime = fakeTime
+	dir := makeTempDir("TestSyncOnLevelParallel", t)
+	defer os.RemoveAll(dir)
+
+	l := NewFileRotater(WithFilename(logFile(dir)), WithSyncLevel(nlog.ERROR))
+	defer l.Close()
+	w := writer.NewParallelMultiWriter(writer.NewParellelWriter(l, nlog.DEBUG, 10))
+	b := []byte("boo!")
+	// levels are passed to rotater by worker
+	w.WriteIfLevel(nlog.INFO, b)
+	w.WriteIfLevel(nlog.ERROR, b)
+	w.WriteIfLevel(nlog.INFO, b)
+	for i := 0; ; i++ {
+		l.mu.Lock()
+		size, writes := l.size, l.writes
+		l.mu.Unlock()
+		if size == int64(3*len(b)) {
+			equals(1, writes, t)
+			break
+		}
+		if i == 100 {
+			t.Fatalf("timeout waiting for worker, %d bytes written", size)
+		}
+		<-time.After(10 * time.Millisecond)
+	}
+	isNil(w.Close(), t)
+	existsWithContent(logFile(dir), bytes.Repeat(b, 3), t)
+}
+
+func TestMinFreeSpace(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestMinFreeSpace", t)
+	defer os.RemoveAll(dir)
+
+	// filesystem of 40 bytes holding only the test directory
+	defer func() { disk_Free = diskFree }()
+	disk_Free = func(string) (uint64, error) {
+		var used int64
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range files {
+			used += f.Size()
+		}
+		return uint64(40 - used), nil
+	}
+
+	l := NewFileRotater(WithFilename(logFile(dir)), WithMaxSize(10), WithMinFreeSpace(20))
+	defer l.Close()
+	var backups []string
+	for _, b := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc"} {
+		l.Write([]byte(b))
+		newFakeTime()
+		backups = append(backups, backupFile(dir))
+		isNil(l.Rotate(), t)
+		<-time.After(10 * time.Millisecond)
+	}
+
+	// 24 bytes of backups leave 16 bytes free, oldest one is removed
+	notExist(backups[0], t)
+	existsWithContent(backups[1], []byte("bbbbbbbb"), t)
+	existsWithContent(backups[2], []byte("cccccccc"), t)
+}
+
+func TestLowSpaceLevel(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestLowSpaceLevel", t)
+	defer os.RemoveAll(dir)
+
+	// free is read by mill goroutine removing backups for free space
+	var free uint64
+	defer func() { disk_Free = diskFree }()
+	disk_Free = func(string) (uint64, error) {
+		return atomic.LoadUint64(&free), nil
+	}
+
+	l := NewFileRotater(WithFilename(logFile(dir)), WithMinFreeSpace(100), WithLowSpaceLevel(nlog.WARNING))
+	defer l.Close()
+	l.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	l.WriteIfLevel(nlog.ERROR, []byte("error\n"))
+	// plain writes have no level
+	l.Write([]byte("plain\n"))
+	existsWithContent(logFile(dir), []byte("error\nplain\n"), t)
+
+	// space is checked again after spaceCheckInterval
+	atomic.StoreUint64(&free, 1000)
+	l.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	setFakeTime(fakeTime().Add(spaceCheckInterval))
+	l.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	existsWithContent(logFile(dir), []byte("error\nplain\ndebug\n"), t)
+}
+
+func TestLowSpaceLevelDefault(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestLowSpaceLevelDefault", t)
+	defer os.RemoveAll(dir)
+
+	defer func() { disk_Free = diskFree }()
+	disk_Free = func(string) (uint64, error) {
+		return 0, nil
+	}
+
+	// unset level keeps WARNING and more severe lines, also under parallel writers
+	l := &Rotater{Filename: logFile(dir), MinFreeSpace: 100, DropOnLowSpace: true}
+	defer l.Close()
+	w := writer.NewParallelMultiWriter(writer.NewParellelWriter(l, nlog.DEBUG, 10))
+	for _, lvl := range []nlog.Level{nlog.DEBUG, nlog.INFO, nlog.WARNING, nlog.ERROR, nlog.FATAL} {
+		w.WriteIfLevel(lvl, []byte(nlog.LevelNames[lvl]+"\n"))
+	}
+	isNil(w.Close(), t)
+	existsWithContent(logFile(dir), []byte("WRN\nERR\nFAT\n"), t)
+}
+
 func TestJson(t *testing.T) {
 	data := []byte(`
 {
@@ -758,7 +1426,7 @@ func fileCount(dir string, exp int, t testing.TB) {
 
 // newFakeTime sets the fake "current time" to two days later.
 func newFakeTime() {
-	fakeCurrentTime = fakeCurrentTime.Add(time.Hour * 24 * 2)
+	setFakeTime(fakeTime().Add(time.Hour * 24 * 2))
 }
 
 func notExist(path string, t testing.TB) {
@@ -0,0 +1,47 @@
+package filerotater
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// HandleSignals rotates the log file whenever one of sigs is received, using
+// SIGHUP and SIGUSR1 if none is given. It is a no-op on platforms without
+// these signals if sigs is empty. Rotation errors are routed to ErrorHandler.
+// Returned function stops handling signals, which is also done by Close.
+func (l *Rotater) HandleSignals(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = rotateSignals
+	}
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ch:
+				l.handleError(l.Rotate())
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			<-exited
+		})
+	}
+	l.mu.Lock()
+	l.stopSignals = append(l.stopSignals, stop)
+	l.mu.Unlock()
+	return stop
+}
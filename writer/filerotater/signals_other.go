@@ -0,0 +1,8 @@
+// +build windows plan9
+
+package filerotater
+
+import "os"
+
+// rotateSignals are signals handled by HandleSignals by default.
+var rotateSignals []os.Signal
@@ -0,0 +1,11 @@
+// +build !windows,!plan9
+
+package filerotater
+
+import (
+	"os"
+	"syscall"
+)
+
+// rotateSignals are signals handled by HandleSignals by default.
+var rotateSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1}
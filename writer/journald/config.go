@@ -0,0 +1,48 @@
+package journald
+
+import "github.com/derkan/nlog"
+
+// Option is function type used for setting journald writer config attributes
+type option func(*Writer)
+
+// WithSocket sets path of journald socket. It defaults to DefaultSocket.
+func WithSocket(socket string) option {
+	return func(c *Writer) {
+		c.Socket = socket
+	}
+}
+
+// WithIdentifier sets SYSLOG_IDENTIFIER field. It defaults to process name.
+func WithIdentifier(identifier string) option {
+	return func(c *Writer) {
+		c.Identifier = identifier
+	}
+}
+
+// WithField adds a field to every entry. Key is converted to a valid journal
+// field name, prefixed with F_ if it is a field set by writer like MESSAGE.
+func WithField(key, value string) option {
+	return func(c *Writer) {
+		if c.Fields == nil {
+			c.Fields = make(map[string]string)
+		}
+		c.Fields[key] = value
+	}
+}
+
+// WithLevel sets most verbose level written. It defaults to nlog.DEBUG.
+func WithLevel(level nlog.Level) option {
+	return func(c *Writer) {
+		c.Level = level
+	}
+}
+
+// NewJournaldWriter returns a new instance of Writer
+func NewJournaldWriter(opts ...option) *Writer {
+	i := &Writer{Level: nlog.DEBUG}
+	// Loop through each option and set
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
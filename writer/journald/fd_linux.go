@@ -0,0 +1,61 @@
+package journald
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sendFd writes data to a sealed memfd, or an unlinked file in /dev/shm if
+// memfd is not available, and passes its descriptor to journald.
+func sendFd(conn *net.UnixConn, addr *net.UnixAddr, data []byte) error {
+	f, err := memfd(data)
+	if err != nil {
+		if f, err = shmFile(data); err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+	rights := syscall.UnixRights(int(f.Fd()))
+	_, _, err = conn.WriteMsgUnix(nil, rights, addr)
+	return err
+}
+
+// memfd returns a sealed memfd holding data.
+func memfd(data []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "journal-entry")
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	seals := unix.F_SEAL_SEAL | unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// shmFile returns an unlinked temporary file in /dev/shm holding data.
+func shmFile(data []byte) (*os.File, error) {
+	f, err := ioutil.TempFile("/dev/shm", "journal-entry.")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package journald
+
+import "net"
+
+// sendFd is not supported outside linux.
+func sendFd(_ *net.UnixConn, _ *net.UnixAddr, _ []byte) error {
+	return errLargeEntry
+}
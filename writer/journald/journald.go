@@ -0,0 +1,276 @@
+// Package journald provides a writer sending log lines to systemd-journald
+// using its native protocol.
+package journald
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/pool"
+	"github.com/derkan/nlog/writer"
+)
+
+// DefaultSocket is path of journald native protocol socket
+const DefaultSocket = "/run/systemd/journal/socket"
+
+const maxFieldNameLen = 64
+
+// priorities maps log levels to journal priorities
+var priorities = map[nlog.Level]string{
+	nlog.FATAL:   "2", // crit
+	nlog.ERROR:   "3", // err
+	nlog.WARNING: "4", // warning
+	nlog.INFO:    "6", // info
+	nlog.DEBUG:   "7", // debug
+}
+
+// reserved are fields set by writer or having a meaning for journald, log
+// fields with these names are prefixed with F_
+var reserved = map[string]bool{
+	"MESSAGE": true, "MESSAGE_ID": true, "PRIORITY": true, "LOGGER": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true, "ERRNO": true,
+	"SYSLOG_IDENTIFIER": true, "SYSLOG_FACILITY": true, "SYSLOG_PID": true, "SYSLOG_TIMESTAMP": true,
+}
+
+// errLargeEntry is returned by sendFd when large entries can not be sent.
+var errLargeEntry = errors.New("journald: entry too large for a datagram")
+
+// ensure we always implement writer.LeveledWriter
+var _ writer.LeveledWriter = (*Writer)(nil)
+
+// Writer sends log lines to journald implementing writer.LeveledWriter.
+//
+// Each line is sent as a journal entry with PRIORITY matching its log level
+// and SYSLOG_IDENTIFIER set to Identifier. Lines written by json formatter
+// have their msg as MESSAGE, logger as LOGGER, loc as CODE_FILE and CODE_LINE
+// and remaining fields as uppercase journal fields. Other lines are sent as
+// MESSAGE as is.
+//
+// Entries which don't fit in a datagram are written to a sealed memfd whose
+// descriptor is passed to journald.
+//
+// Writers are built with NewJournaldWriter. Level of a zero Writer is
+// nlog.FATAL, so it writes only FATAL lines unless Level is set.
+type Writer struct {
+	// Socket is path of journald socket. It defaults to DefaultSocket.
+	Socket string `json:"socket" yaml:"socket"`
+
+	// Identifier is SYSLOG_IDENTIFIER field. It defaults to process name.
+	Identifier string `json:"identifier" yaml:"identifier"`
+
+	// Fields are added to every entry.
+	Fields map[string]string `json:"fields" yaml:"fields"`
+
+	// Level is most verbose level written. NewJournaldWriter sets it to
+	// nlog.DEBUG, it is nlog.FATAL for a zero Writer.
+	Level nlog.Level `json:"level" yaml:"level"`
+
+	// MinLevel is most severe level written. The default is nlog.FATAL,
+	// writing all levels up to Level.
+	MinLevel nlog.Level `json:"min_level" yaml:"min_level"`
+
+	once sync.Once
+	conn *net.UnixConn
+	addr *net.UnixAddr
+	err  error
+}
+
+// Write implements io.Writer. Priority is taken from level field of lines
+// written by json formatter, INFO is used otherwise.
+func (l *Writer) Write(p []byte) (n int, err error) {
+	lvl := nlog.INFO
+	if e, ok := writer.ParseJSONLine(p); ok {
+		if v, ok := writer.LevelFromString(e.Level); ok {
+			lvl = v
+		}
+	}
+	return l.WriteIfLevel(lvl, p)
+}
+
+// WriteIfLevel sends p with priority of given level if level is satisfied.
+func (l *Writer) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	if !l.Enabled(lvl) {
+		if p == nil {
+			return 0, nil
+		}
+		return len(p), nil
+	}
+	l.once.Do(l.init)
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	buf := pool.GetBuffer()
+	defer pool.PutBuffer(buf)
+	l.encode(buf, lvl, p)
+	if err = l.send(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// GetLevel returns log level of current writer
+func (l *Writer) GetLevel() nlog.Level {
+	return l.Level
+}
+
+// Enabled returns true if lvl is between MinLevel and Level.
+func (l *Writer) Enabled(lvl nlog.Level) bool {
+	return lvl >= l.MinLevel && lvl <= l.Level
+}
+
+// Sync does nothing, entries are sent on write.
+func (l *Writer) Sync() error {
+	return nil
+}
+
+// Describe returns info of writer with levels it writes, see writer.Describe
+func (l *Writer) Describe() writer.Info {
+	return writer.Info{Type: "journald", Name: l.Socket, Levels: writer.LevelRange(l.MinLevel, l.Level).String()}
+}
+
+// Close implements io.Closer.
+func (l *Writer) Close() error {
+	l.once.Do(l.init)
+	if l.conn == nil {
+		return nil
+	}
+	return l.conn.Close()
+}
+
+// init sets defaults and opens an unbound datagram socket.
+func (l *Writer) init() {
+	if l.Socket == "" {
+		l.Socket = DefaultSocket
+	}
+	if l.Identifier == "" {
+		l.Identifier = filepath.Base(os.Args[0])
+	}
+	l.addr = &net.UnixAddr{Name: l.Socket, Net: "unixgram"}
+	l.conn, l.err = net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+}
+
+// send sends an encoded entry, passing it in a file descriptor if it is too
+// large for a datagram.
+func (l *Writer) send(data []byte) error {
+	_, _, err := l.conn.WriteMsgUnix(data, nil, l.addr)
+	if err == nil || !isTooLarge(err) {
+		return err
+	}
+	return sendFd(l.conn, l.addr, data)
+}
+
+// encode writes journal entry for p into buf.
+func (l *Writer) encode(buf nlog.Buffer, lvl nlog.Level, p []byte) {
+	prio, ok := priorities[lvl]
+	if !ok {
+		prio = priorities[nlog.WARNING]
+	}
+	appendField(buf, "PRIORITY", prio)
+	appendField(buf, "SYSLOG_IDENTIFIER", l.Identifier)
+	if len(l.Fields) > 0 {
+		keys := make([]string, 0, len(l.Fields))
+		for k := range l.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			appendField(buf, userFieldName(k), l.Fields[k])
+		}
+	}
+
+	e, ok := writer.ParseJSONLine(p)
+	if !ok {
+		appendField(buf, "MESSAGE", string(trimNewline(p)))
+		return
+	}
+	appendField(buf, "MESSAGE", e.Message)
+	if e.Logger != "" {
+		appendField(buf, "LOGGER", e.Logger)
+	}
+	for _, f := range e.Fields {
+		if f.Key == "loc" {
+			if i := strings.LastIndexByte(f.Value, ':'); i > 0 {
+				appendField(buf, "CODE_FILE", f.Value[:i])
+				appendField(buf, "CODE_LINE", f.Value[i+1:])
+				continue
+			}
+		}
+		appendField(buf, userFieldName(f.Key), f.Value)
+	}
+}
+
+// userFieldName returns FieldName of key, prefixed with F_ if it is reserved
+// so that it does not duplicate fields set by writer.
+func userFieldName(key string) string {
+	name := FieldName(key)
+	if reserved[name] {
+		return "F_" + name
+	}
+	return name
+}
+
+// appendField appends a field in journal export format, using binary safe
+// form for values containing newlines.
+func appendField(buf nlog.Buffer, key, val string) {
+	buf.AppendString(key, false)
+	if strings.IndexByte(val, '\n') < 0 {
+		buf.AppendByte('=').AppendString(val, false).AppendByte('\n')
+		return
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(val)))
+	buf.AppendByte('\n').AppendBytes(size[:]).AppendString(val, false).AppendByte('\n')
+}
+
+// FieldName converts key to a valid journal field name: uppercase letters,
+// digits and underscores, not starting with an underscore or a digit and at
+// most 64 characters long.
+func FieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	for len(name) > 0 && name[0] == '_' {
+		name = name[1:]
+	}
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = append([]byte("F_"), name...)
+	}
+	if len(name) > maxFieldNameLen {
+		name = name[:maxFieldNameLen]
+	}
+	return string(name)
+}
+
+// trimNewline removes trailing line feeds from p.
+func trimNewline(p []byte) []byte {
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// isTooLarge reports whether err is caused by a datagram being too large.
+func isTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// Enabled reports whether journald socket exists.
+func Enabled(socket string) bool {
+	if socket == "" {
+		socket = DefaultSocket
+	}
+	_, err := os.Stat(socket)
+	return err == nil
+}
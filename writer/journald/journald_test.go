@@ -0,0 +1,166 @@
+// +build linux
+
+package journald
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
+// listenJournal creates a datagram socket in a temporary directory acting as
+// journald.
+func listenJournal(t *testing.T) (*net.UnixConn, string, func()) {
+	dir, err := ioutil.TempDir("", "TestJournald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Skipf("unixgram sockets not supported: %v", err)
+	}
+	return conn, socket, func() {
+		conn.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// readEntry reads an entry from conn, reading it from passed descriptor if
+// one is sent.
+func readEntry(t *testing.T, conn *net.UnixConn) []byte {
+	t.Helper()
+	buf := make([]byte, 1<<16)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return buf[:n]
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("can't parse control message: %v", err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("can't parse unix rights: %v", err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "entry")
+	defer f.Close()
+	// journald maps the whole file, offset left by writer is ignored
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEncodeJSONLine(t *testing.T) {
+	conn, socket, cleanup := listenJournal(t)
+	defer cleanup()
+
+	w := NewJournaldWriter(WithSocket(socket), WithIdentifier("app"), WithField("env", "test"))
+	defer w.Close()
+	line := `{"time":"2026/10/18 21:30:05","level":"ERR","logger":"db","msg":"query failed","loc":"main.go:42","user-id":7}` + "\n"
+	if _, err := w.WriteIfLevel(nlog.ERROR, []byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	want := "PRIORITY=3\nSYSLOG_IDENTIFIER=app\nENV=test\nMESSAGE=query failed\nLOGGER=db\n" +
+		"CODE_FILE=main.go\nCODE_LINE=42\nUSER_ID=7\n"
+	if got := string(readEntry(t, conn)); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestEncodeMultilineMessage(t *testing.T) {
+	conn, socket, cleanup := listenJournal(t)
+	defer cleanup()
+
+	w := NewJournaldWriter(WithSocket(socket), WithIdentifier("app"))
+	defer w.Close()
+	w.WriteIfLevel(nlog.WARNING, []byte("first\nsecond\n"))
+	msg := "first\nsecond"
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(msg)))
+	want := "PRIORITY=4\nSYSLOG_IDENTIFIER=app\nMESSAGE\n" + string(size) + msg + "\n"
+	if got := string(readEntry(t, conn)); got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+}
+
+func TestLargeEntry(t *testing.T) {
+	conn, socket, cleanup := listenJournal(t)
+	defer cleanup()
+
+	w := NewJournaldWriter(WithSocket(socket), WithIdentifier("app"))
+	defer w.Close()
+	msg := strings.Repeat("x", 1<<18)
+	if _, err := w.WriteIfLevel(nlog.INFO, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	want := "PRIORITY=6\nSYSLOG_IDENTIFIER=app\nMESSAGE=" + msg + "\n"
+	if got := string(readEntry(t, conn)); got != want {
+		t.Fatalf("want entry of %d bytes, got %d bytes", len(want), len(got))
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	conn, socket, cleanup := listenJournal(t)
+	defer cleanup()
+
+	w := NewJournaldWriter(WithSocket(socket), WithIdentifier("app"), WithLevel(nlog.WARNING))
+	defer w.Close()
+	w.WriteIfLevel(nlog.DEBUG, []byte("skipped"))
+	w.Write([]byte(`{"level":"ERR","msg":"sent"}`))
+	want := "PRIORITY=3\nSYSLOG_IDENTIFIER=app\nMESSAGE=sent\n"
+	if got := string(readEntry(t, conn)); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestReservedFieldNames(t *testing.T) {
+	conn, socket, cleanup := listenJournal(t)
+	defer cleanup()
+
+	w := NewJournaldWriter(WithSocket(socket), WithIdentifier("app"), WithField("priority", "high"))
+	defer w.Close()
+	line := `{"level":"INF","msg":"sent","message":"body","syslog_identifier":"other","user":"u1"}` + "\n"
+	if _, err := w.WriteIfLevel(nlog.INFO, []byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	want := "PRIORITY=6\nSYSLOG_IDENTIFIER=app\nF_PRIORITY=high\nMESSAGE=sent\n" +
+		"F_MESSAGE=body\nF_SYSLOG_IDENTIFIER=other\nUSER=u1\n"
+	if got := string(readEntry(t, conn)); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user":      "USER",
+		"user-id":   "USER_ID",
+		"_private":  "PRIVATE",
+		"1st":       "F_1ST",
+		"":          "F_",
+		"req.trace": "REQ_TRACE",
+	} {
+		if got := FieldName(key); got != want {
+			t.Errorf("FieldName(%q): want %q, got %q", key, want, got)
+		}
+	}
+}
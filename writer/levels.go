@@ -0,0 +1,60 @@
+package writer
+
+import (
+	"strings"
+
+	"github.com/derkan/nlog"
+)
+
+// LevelSet is a set of log levels written by a writer
+type LevelSet uint8
+
+// AllLevels contains all log levels
+const AllLevels = LevelSet(1<<(uint(nlog.DEBUG)+1) - 1)
+
+// NewLevelSet returns a set of given levels
+func NewLevelSet(lvls ...nlog.Level) LevelSet {
+	var s LevelSet
+	for _, lvl := range lvls {
+		if lvl >= nlog.FATAL && lvl <= nlog.DEBUG {
+			s |= 1 << uint(lvl)
+		}
+	}
+	return s
+}
+
+// LevelRange returns a set of levels from min to max, where min is the most
+// severe level, like WARNING, and max is the least severe level, like INFO.
+func LevelRange(min, max nlog.Level) LevelSet {
+	var s LevelSet
+	for lvl := min; lvl <= max; lvl++ {
+		s |= NewLevelSet(lvl)
+	}
+	return s
+}
+
+// Has returns true if lvl is in set
+func (s LevelSet) Has(lvl nlog.Level) bool {
+	return lvl >= nlog.FATAL && lvl <= nlog.DEBUG && s&(1<<uint(lvl)) != 0
+}
+
+// Max returns the least severe level in set. Returns nlog.FATAL for empty set.
+func (s LevelSet) Max() nlog.Level {
+	for lvl := nlog.DEBUG; lvl > nlog.FATAL; lvl-- {
+		if s.Has(lvl) {
+			return lvl
+		}
+	}
+	return nlog.FATAL
+}
+
+// String returns level names in set separated by commas
+func (s LevelSet) String() string {
+	var names []string
+	for lvl := nlog.FATAL; lvl <= nlog.DEBUG; lvl++ {
+		if s.Has(lvl) {
+			names = append(names, nlog.LevelNames[lvl])
+		}
+	}
+	return strings.Join(names, ",")
+}
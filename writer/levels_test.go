@@ -0,0 +1,52 @@
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/derkan/nlog"
+)
+
+func TestLevelSet(t *testing.T) {
+	s := LevelRange(nlog.WARNING, nlog.INFO)
+	for lvl, want := range map[nlog.Level]bool{
+		nlog.FATAL:   false,
+		nlog.ERROR:   false,
+		nlog.WARNING: true,
+		nlog.INFO:    true,
+		nlog.DEBUG:   false,
+	} {
+		if got := s.Has(lvl); got != want {
+			t.Errorf("Has(%v): want %v, got %v", lvl, want, got)
+		}
+	}
+	if s.Max() != nlog.INFO {
+		t.Errorf("expected max INFO, got %v", s.Max())
+	}
+	if got := s.String(); got != "WRN,INF" {
+		t.Errorf("expected WRN,INF, got %s", got)
+	}
+	if got := NewLevelSet(nlog.FATAL, nlog.DEBUG, nlog.Level(9)); got.String() != "FAT,DBG" {
+		t.Errorf("expected FAT,DBG, got %s", got)
+	}
+	if LevelRange(nlog.FATAL, nlog.DEBUG) != AllLevels {
+		t.Errorf("expected all levels")
+	}
+}
+
+func TestMultiWriterLevels(t *testing.T) {
+	some, all := &recorder{}, &recorder{}
+	mw := NewMultiWriter(
+		NewWriterLevels(some, NewLevelSet(nlog.ERROR, nlog.INFO)),
+		NewWriter(all, nlog.DEBUG),
+	)
+	for lvl := nlog.FATAL; lvl <= nlog.DEBUG; lvl++ {
+		mw.WriteIfLevel(lvl, []byte(nlog.LevelNames[lvl]))
+	}
+	if got, want := some.get(), []string{nlog.ErrorStr, nlog.InfoStr}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got := all.get(); len(got) != 5 {
+		t.Fatalf("expected all levels written, got %q", got)
+	}
+}
@@ -0,0 +1,55 @@
+// +build linux
+
+package writer
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
+func TestRingWriterDumpOnSignal(t *testing.T) {
+	rec := &recorder{}
+	r := NewRingWriter(rec, nlog.INFO, 10, nlog.ERROR)
+	stop := r.DumpOnSignal(syscall.SIGUSR2)
+	defer stop()
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(rec.get()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("ring not dumped on signal")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := rec.get(); got[0] != "debug\n" {
+		t.Fatalf("expected %q, got %q", "debug\n", got[0])
+	}
+}
+
+func TestRingWriterCloseStopsSignals(t *testing.T) {
+	rec := &recorder{}
+	r := NewRingWriter(rec, nlog.INFO, 10, nlog.ERROR)
+	r.DumpOnSignal(syscall.SIGUSR2)
+	// keep SIGUSR2 caught after ring stops handling it
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	r.Close()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	time.Sleep(20 * time.Millisecond)
+	if got := rec.get(); len(got) != 0 {
+		t.Fatalf("expected no dump after Close, got %q", got)
+	}
+}
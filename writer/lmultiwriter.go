@@ -14,6 +14,8 @@ type LeveledMultiWriter interface {
 	WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error)
 	Remove(writers ...LeveledWriter)
 	Append(writers ...LeveledWriter)
+	// Sync commits written data of all writers to stable storage
+	Sync() error
 }
 
 // MultiWriter is multi writer with log level filtering
@@ -72,13 +74,25 @@ func (t *MultiWriter) Close() (err error) {
 	return
 }
 
+// Sync calls Sync of all writers.
+func (t *MultiWriter) Sync() (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, w := range t.writers {
+		if werr := w.Sync(); werr != nil {
+			err = fmt.Errorf("%v, worker: %d, err: %v", err, i, werr)
+		}
+	}
+	return
+}
+
 // WriteIfLevel calls write if current le₺vel is satisfied
 func (t *MultiWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	for i, w := range t.writers {
-		if lvl > w.GetLevel() {
+		if !w.Enabled(lvl) {
 			continue
 		}
 		if _, werr := w.WriteIfLevel(lvl, p); err != nil {
@@ -1,7 +1,9 @@
 package writer
 
 import (
+	"errors"
 	"io"
+	"syscall"
 
 	"github.com/derkan/nlog"
 )
@@ -11,6 +13,29 @@ type LeveledWriter interface {
 	io.WriteCloser
 	WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error)
 	GetLevel() nlog.Level
+	// Enabled returns true if lines of lvl are written
+	Enabled(lvl nlog.Level) bool
+	// Sync commits written data to stable storage if writer supports it
+	Sync() error
+}
+
+// Syncer is implemented by writers which can commit written data to stable
+// storage, like *os.File
+type Syncer interface {
+	Sync() error
+}
+
+// SyncWriter calls Sync of w if it implements Syncer. Errors of files which
+// can't be synced, like terminals and pipes, are ignored.
+func SyncWriter(w io.Writer) error {
+	s, ok := w.(Syncer)
+	if !ok {
+		return nil
+	}
+	if err := s.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
 }
 
 // Writer is writer with log level filtering
@@ -19,6 +44,7 @@ type LeveledWriter interface {
 type Writer struct {
 	w io.WriteCloser
 	l nlog.Level
+	s LevelSet
 }
 
 // Write implements io.Writer.
@@ -31,14 +57,24 @@ func (l *Writer) Close() (err error) {
 	return l.w.Close()
 }
 
+// Sync calls Sync of wrapped writer if it implements Syncer.
+func (l *Writer) Sync() error {
+	return SyncWriter(l.w)
+}
+
 // GetLevel returns log level of current writer
 func (l *Writer) GetLevel() nlog.Level {
 	return l.l
 }
 
+// Enabled returns true if lvl is in levels of current writer
+func (l *Writer) Enabled(lvl nlog.Level) bool {
+	return l.s.Has(lvl)
+}
+
 // WriteIfLevel calls write if current le₺vel is satisfied
 func (l *Writer) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
-	if lvl > l.l {
+	if !l.s.Has(lvl) {
 		if p == nil {
 			return 0, nil
 		}
@@ -53,8 +89,15 @@ func (l *Writer) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
 // NewWriter returns a new instance of threadsafe writer which will
 // write when level is satisfied
 func NewWriter(w io.WriteCloser, l nlog.Level) *Writer {
+	return NewWriterLevels(w, LevelRange(nlog.FATAL, l))
+}
+
+// NewWriterLevels returns a new instance of writer which will write only
+// lines of levels in s, like LevelRange(nlog.WARNING, nlog.INFO)
+func NewWriterLevels(w io.WriteCloser, s LevelSet) *Writer {
 	return &Writer{
 		w: w,
-		l: l,
+		l: s.Max(),
+		s: s,
 	}
 }
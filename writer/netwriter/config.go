@@ -0,0 +1,76 @@
+package netwriter
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Option is function type used for setting network writer config attributes
+type option func(*Writer)
+
+// WithAddress sets network and remote address to write to.
+// Network is one of tcp, tcp4, tcp6, udp, udp4, udp6, unix or unixgram.
+func WithAddress(network, address string) option {
+	return func(c *Writer) {
+		c.Network = network
+		c.Address = address
+	}
+}
+
+// WithFraming sets how messages are delimited on stream connections.
+// It defaults to NewlineFraming.
+func WithFraming(framing Framing) option {
+	return func(c *Writer) {
+		c.Framing = framing
+	}
+}
+
+// WithTLS enables TLS for tcp connections with given config.
+func WithTLS(cfg *tls.Config) option {
+	return func(c *Writer) {
+		c.TLSConfig = cfg
+	}
+}
+
+// WithDialTimeout sets the maximum time to wait for a connection.
+// It defaults to 5 seconds.
+func WithDialTimeout(timeout time.Duration) option {
+	return func(c *Writer) {
+		c.DialTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the maximum time a single write may take.
+// It defaults to 5 seconds.
+func WithWriteTimeout(timeout time.Duration) option {
+	return func(c *Writer) {
+		c.WriteTimeout = timeout
+	}
+}
+
+// WithBackoff sets minimum and maximum wait time between reconnection
+// attempts. They default to 100 milliseconds and 30 seconds.
+func WithBackoff(min, max time.Duration) option {
+	return func(c *Writer) {
+		c.MinBackoff = min
+		c.MaxBackoff = max
+	}
+}
+
+// WithBufferSize sets the maximum number of bytes kept in memory while
+// disconnected. It defaults to 1 megabyte, a negative value disables buffering.
+func WithBufferSize(size int) option {
+	return func(c *Writer) {
+		c.BufferSize = size
+	}
+}
+
+// NewNetWriter returns a new instance of Writer
+func NewNetWriter(opts ...option) *Writer {
+	i := &Writer{}
+	// Loop through each option and set
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
@@ -0,0 +1,488 @@
+// Package netwriter provides a writer sending log lines to TCP, UDP or Unix
+// sockets.
+package netwriter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/derkan/nlog/writer"
+)
+
+// Framing defines how messages are delimited on stream connections.
+type Framing int
+
+const (
+	// NewlineFraming terminates each message with a line feed.
+	NewlineFraming Framing = iota
+	// OctetCountingFraming prefixes each message with its length in bytes
+	// followed by a space, as described in RFC 6587.
+	OctetCountingFraming
+)
+
+const (
+	defaultNetwork      = "tcp"
+	defaultDialTimeout  = 5 * time.Second
+	defaultWriteTimeout = 5 * time.Second
+	defaultMinBackoff   = 100 * time.Millisecond
+	defaultMaxBackoff   = 30 * time.Second
+	defaultBufferSize   = 1024 * 1024
+)
+
+// ensure we always implement io.WriteCloser and writer.Describer
+var (
+	_ io.WriteCloser   = (*Writer)(nil)
+	_ writer.Describer = (*Writer)(nil)
+)
+
+// ErrClosed is returned when writing to a closed Writer.
+var ErrClosed = errors.New("netwriter: write to closed writer")
+
+// Writer is an io.WriteCloser that sends each written message to a network
+// address.
+//
+// The connection is opened on first Write. If the connection can not be
+// established or breaks while writing, messages are kept in an in-memory
+// buffer of at most BufferSize bytes and the connection is retried in
+// background, waiting between MinBackoff and MaxBackoff (doubling on each
+// failure) between attempts, so writes do not wait for dials. When the
+// buffer is full the oldest messages are dropped, see Dropped. Only unsent
+// bytes of a message partially written to a broken stream connection are
+// sent after reconnecting.
+//
+// On stream networks (tcp, unix) messages are delimited according to
+// Framing. On datagram networks (udp, unixgram) each message is sent as a
+// single datagram without its trailing newline.
+type Writer struct {
+	// Network is one of tcp, tcp4, tcp6, udp, udp4, udp6, unix or unixgram.
+	// It defaults to tcp.
+	Network string `json:"network" yaml:"network"`
+
+	// Address is the remote address, host:port for ip networks or a socket
+	// path for unix networks.
+	Address string `json:"address" yaml:"address"`
+
+	// Framing sets how messages are delimited on stream connections. It
+	// defaults to NewlineFraming.
+	Framing Framing `json:"framing" yaml:"framing"`
+
+	// TLSConfig enables TLS on tcp connections when set.
+	TLSConfig *tls.Config `json:"-" yaml:"-"`
+
+	// DialTimeout is the maximum time to wait for a connection. It defaults to
+	// 5 seconds.
+	DialTimeout time.Duration `json:"dial_timeout" yaml:"dial_timeout"`
+
+	// WriteTimeout is the maximum time a single write may take. It defaults
+	// to 5 seconds, a negative value disables write deadlines.
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout"`
+
+	// MinBackoff and MaxBackoff bound the wait time between reconnection
+	// attempts. They default to 100 milliseconds and 30 seconds.
+	MinBackoff time.Duration `json:"min_backoff" yaml:"min_backoff"`
+	MaxBackoff time.Duration `json:"max_backoff" yaml:"max_backoff"`
+
+	// BufferSize is the maximum number of bytes kept in memory while
+	// disconnected. It defaults to 1 megabyte, a negative value disables
+	// buffering.
+	BufferSize int `json:"buffer_size" yaml:"buffer_size"`
+
+	mu          sync.Mutex
+	conn        net.Conn
+	pending     [][]byte
+	pendingSize int
+	dropped     uint64
+	backoff     time.Duration
+	nextDial    time.Time
+	closed      bool
+	stop        chan struct{} // closed by Close to stop reconnecting
+	dialDone    chan struct{} // closed when reconnecting goroutine exits
+}
+
+var (
+	// currentTime exists so it can be mocked out by tests.
+	currentTime = time.Now
+	// dialConn exists so it can be mocked out by tests.
+	dialConn = func(d *net.Dialer, network, address string) (net.Conn, error) {
+		return d.Dial(network, address)
+	}
+)
+
+// Write implements io.Writer. The message is sent immediately if the
+// connection is up, otherwise it is buffered until the connection is
+// restored in background.
+func (l *Writer) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrClosed
+	}
+	msg := l.frame(p)
+	if l.conn == nil {
+		l.enqueue(msg)
+		l.reconnect()
+		return len(p), nil
+	}
+	if err := l.flushPending(); err != nil {
+		l.enqueue(msg)
+		l.reconnect()
+		return len(p), nil
+	}
+	if sent, err := l.send(msg); err != nil {
+		l.enqueue(msg[sent:])
+		l.reconnect()
+	}
+	return len(p), nil
+}
+
+// Flush tries to send buffered messages. If there is no connection,
+// reconnecting is started in background and an error is returned.
+func (l *Writer) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if len(l.pending) == 0 {
+		return nil
+	}
+	if l.conn == nil {
+		l.reconnect()
+		return fmt.Errorf("netwriter: not connected to %s, %d bytes buffered", l.Address, l.pendingSize)
+	}
+	if err := l.flushPending(); err != nil {
+		l.reconnect()
+		return err
+	}
+	return nil
+}
+
+// Sync implements writer.Syncer, it is same as Flush.
+func (l *Writer) Sync() error {
+	return l.Flush()
+}
+
+// Close implements io.Closer. It stops reconnecting, makes a last attempt to
+// send buffered messages and closes the connection.
+func (l *Writer) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.stop != nil {
+		close(l.stop)
+	}
+	dialDone := l.dialDone
+	l.mu.Unlock()
+	if dialDone != nil {
+		<-dialDone
+	}
+
+	// writes fail with ErrClosed from now on, dial without holding lock
+	var err error
+	l.mu.Lock()
+	redial := l.conn == nil && len(l.pending) > 0
+	l.mu.Unlock()
+	if redial {
+		conn, errDial := l.dial()
+		l.mu.Lock()
+		if err = errDial; err == nil {
+			l.conn = conn
+		}
+		l.mu.Unlock()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		err = l.flushPending()
+	}
+	l.pending = nil
+	l.pendingSize = 0
+	if l.conn != nil {
+		if errClose := l.conn.Close(); err == nil {
+			err = errClose
+		}
+		l.conn = nil
+	}
+	return err
+}
+
+// Describe returns info of writer with its network and address, see
+// writer.Describe
+func (l *Writer) Describe() writer.Info {
+	return writer.Info{Type: l.network(), Name: l.Address}
+}
+
+// Dropped returns the number of messages dropped because the buffer was full.
+func (l *Writer) Dropped() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped
+}
+
+// Buffered returns the number of bytes waiting to be sent.
+func (l *Writer) Buffered() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.pendingSize
+}
+
+// frame copies p into a new message framed for the network.
+func (l *Writer) frame(p []byte) []byte {
+	if l.datagram() {
+		return append([]byte(nil), trimNewline(p)...)
+	}
+	if l.Framing == OctetCountingFraming {
+		p = trimNewline(p)
+		msg := make([]byte, 0, len(p)+8)
+		msg = strconv.AppendInt(msg, int64(len(p)), 10)
+		msg = append(msg, ' ')
+		return append(msg, p...)
+	}
+	msg := make([]byte, 0, len(p)+1)
+	msg = append(msg, p...)
+	if len(msg) == 0 || msg[len(msg)-1] != '\n' {
+		msg = append(msg, '\n')
+	}
+	return msg
+}
+
+// reconnect starts dialing in background if it is not running. It is called
+// with mu held.
+func (l *Writer) reconnect() {
+	if l.dialDone != nil || l.closed {
+		return
+	}
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+	}
+	l.dialDone = make(chan struct{})
+	go l.reconnectRun(l.stop, l.dialDone)
+}
+
+// reconnectRun dials until connected and buffered messages are sent, waiting
+// for backoff between attempts, or until stop is closed.
+func (l *Writer) reconnectRun(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		l.mu.Lock()
+		wait := l.nextDial.Sub(currentTime())
+		l.mu.Unlock()
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-stop:
+				t.Stop()
+				return
+			}
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		conn, err := l.dial()
+
+		l.mu.Lock()
+		if l.closed {
+			l.dialDone = nil
+			l.mu.Unlock()
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			l.backoff *= 2
+			if l.backoff < l.minBackoff() {
+				l.backoff = l.minBackoff()
+			}
+			if l.backoff > l.maxBackoff() {
+				l.backoff = l.maxBackoff()
+			}
+			l.nextDial = currentTime().Add(l.backoff)
+			l.mu.Unlock()
+			continue
+		}
+		l.conn = conn
+		l.backoff = 0
+		if l.flushPending() == nil {
+			l.dialDone = nil
+			l.mu.Unlock()
+			return
+		}
+		l.nextDial = currentTime().Add(l.minBackoff())
+		l.mu.Unlock()
+	}
+}
+
+// dial opens a new connection.
+func (l *Writer) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: l.dialTimeout()}
+	if l.TLSConfig != nil && !l.datagram() {
+		return tls.DialWithDialer(d, l.network(), l.Address, l.TLSConfig)
+	}
+	return dialConn(d, l.network(), l.Address)
+}
+
+// send writes msg to the connection, closing it on failure. It returns number
+// of bytes of msg sent, which are 0 for datagrams which are sent whole.
+func (l *Writer) send(msg []byte) (int, error) {
+	if l.WriteTimeout >= 0 {
+		l.conn.SetWriteDeadline(time.Now().Add(l.writeTimeout()))
+	}
+	n, err := l.conn.Write(msg)
+	if err != nil {
+		l.conn.Close()
+		l.conn = nil
+		if l.datagram() || n < 0 {
+			n = 0
+		}
+	}
+	return n, err
+}
+
+// flushPending sends buffered messages in order. Sent bytes of a message
+// partially written are removed from buffer.
+func (l *Writer) flushPending() error {
+	for len(l.pending) > 0 {
+		msg := l.pending[0]
+		n, err := l.send(msg)
+		if err != nil {
+			l.pending[0] = msg[n:]
+			l.pendingSize -= n
+			return err
+		}
+		l.pending[0] = nil
+		l.pending = l.pending[1:]
+		l.pendingSize -= len(msg)
+	}
+	l.pending = nil
+	return nil
+}
+
+// enqueue buffers msg, dropping oldest messages if buffer limit is exceeded.
+func (l *Writer) enqueue(msg []byte) {
+	max := l.bufferSize()
+	if len(msg) > max {
+		l.dropped++
+		return
+	}
+	l.pending = append(l.pending, msg)
+	l.pendingSize += len(msg)
+	for l.pendingSize > max {
+		l.pendingSize -= len(l.pending[0])
+		l.pending[0] = nil
+		l.pending = l.pending[1:]
+		l.dropped++
+	}
+}
+
+// datagram reports whether the network is message oriented.
+func (l *Writer) datagram() bool {
+	switch l.network() {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+	return false
+}
+
+func (l *Writer) network() string {
+	if l.Network == "" {
+		return defaultNetwork
+	}
+	return l.Network
+}
+
+func (l *Writer) dialTimeout() time.Duration {
+	if l.DialTimeout == 0 {
+		return defaultDialTimeout
+	}
+	return l.DialTimeout
+}
+
+func (l *Writer) writeTimeout() time.Duration {
+	if l.WriteTimeout == 0 {
+		return defaultWriteTimeout
+	}
+	return l.WriteTimeout
+}
+
+func (l *Writer) minBackoff() time.Duration {
+	if l.MinBackoff == 0 {
+		return defaultMinBackoff
+	}
+	return l.MinBackoff
+}
+
+func (l *Writer) maxBackoff() time.Duration {
+	if l.MaxBackoff == 0 {
+		return defaultMaxBackoff
+	}
+	return l.MaxBackoff
+}
+
+func (l *Writer) bufferSize() int {
+	if l.BufferSize == 0 {
+		return defaultBufferSize
+	}
+	if l.BufferSize < 0 {
+		return 0
+	}
+	return l.BufferSize
+}
+
+// trimNewline removes a single trailing line feed from p.
+func trimNewline(p []byte) []byte {
+	if len(p) > 0 && p[len(p)-1] == '\n' {
+		return p[:len(p)-1]
+	}
+	return p
+}
+
+// LoadTLSConfig builds a TLS config from PEM files. caFile sets the root
+// certificates used to verify the server, certFile and keyFile set the client
+// certificate. Empty file names are skipped.
+func LoadTLSConfig(caFile, certFile, keyFile string, skipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: skipVerify}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA file: %s", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %s", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ParseFraming returns framing for given name, which can be newline or
+// octet. Unknown names return NewlineFraming.
+func ParseFraming(name string) Framing {
+	switch name {
+	case "octet", "octet_counting", "octet-counting":
+		return OctetCountingFraming
+	}
+	return NewlineFraming
+}
@@ -0,0 +1,290 @@
+package netwriter
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog/writer"
+)
+
+// acceptLines accepts a single connection on ln and sends each line read
+// from it to returned channel.
+func acceptLines(t *testing.T, ln net.Listener) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+	return lines
+}
+
+func expectLine(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-lines:
+		if got != want {
+			t.Fatalf("want line %q, got %q", want, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for line %q", want)
+	}
+}
+
+func TestTCPNewline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := acceptLines(t, ln)
+
+	w := NewNetWriter(WithAddress("tcp", ln.Addr().String()))
+	defer w.Close()
+	for _, msg := range []string{"first\n", "second"} {
+		if n, err := w.Write([]byte(msg)); err != nil || n != len(msg) {
+			t.Fatalf("write failed, n: %d, err: %v", n, err)
+		}
+	}
+	expectLine(t, lines, "first")
+	expectLine(t, lines, "second")
+}
+
+func TestTCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := acceptLines(t, ln)
+
+	w := NewNetWriter(WithAddress("tcp", ln.Addr().String()), WithFraming(OctetCountingFraming))
+	w.Write([]byte("hello\n"))
+	w.Write([]byte("world\n"))
+	w.Close()
+	expectLine(t, lines, "5 hello5 world")
+}
+
+func TestUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w := NewNetWriter(WithAddress("udp", pc.LocalAddr().String()))
+	defer w.Close()
+	w.Write([]byte("datagram\n"))
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "datagram" {
+		t.Fatalf("want datagram %q, got %q", "datagram", got)
+	}
+}
+
+func TestUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestUnix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "log.sock")
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+	defer ln.Close()
+	lines := acceptLines(t, ln)
+
+	w := NewNetWriter(WithAddress("unix", addr))
+	defer w.Close()
+	w.Write([]byte("over unix\n"))
+	expectLine(t, lines, "over unix")
+}
+
+func TestReconnectBuffered(t *testing.T) {
+	now := time.Now()
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = time.Now }()
+
+	// reserve a free port and close it so first dial fails
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := NewNetWriter(WithAddress("tcp", addr), WithBackoff(time.Second, time.Minute))
+	defer w.Close()
+	w.Write([]byte("one\n"))
+	if got := w.Buffered(); got != 4 {
+		t.Fatalf("want 4 bytes buffered, got %d", got)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("can't listen again on %s: %v", addr, err)
+	}
+	defer ln.Close()
+	lines := acceptLines(t, ln)
+
+	// still in backoff, message must be buffered
+	w.Write([]byte("two\n"))
+	if got := w.Buffered(); got != 8 {
+		t.Fatalf("want 8 bytes buffered during backoff, got %d", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	w.Write([]byte("three\n"))
+	expectLine(t, lines, "one")
+	expectLine(t, lines, "two")
+	expectLine(t, lines, "three")
+	if got := w.Buffered(); got != 0 {
+		t.Fatalf("want empty buffer after reconnect, got %d", got)
+	}
+}
+
+func TestBufferDropsOldest(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := NewNetWriter(WithAddress("tcp", addr), WithBufferSize(8), WithBackoff(time.Hour, time.Hour))
+	defer w.Close()
+	w.Write([]byte("aaa\n"))
+	w.Write([]byte("bbb\n"))
+	w.Write([]byte("ccc\n"))
+	if got := w.Dropped(); got != 1 {
+		t.Fatalf("want 1 dropped message, got %d", got)
+	}
+	if got := w.Buffered(); got != 8 {
+		t.Fatalf("want 8 bytes buffered, got %d", got)
+	}
+	if got := string(w.pending[0]); got != "bbb\n" {
+		t.Fatalf("want oldest message to be dropped, first pending is %q", got)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	w := NewNetWriter(WithAddress("udp", "127.0.0.1:9"))
+	w.Close()
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+}
+
+// partialConn accepts n bytes, failing writes after them
+type partialConn struct {
+	net.Conn
+	n int
+}
+
+func (c *partialConn) Write(p []byte) (int, error) {
+	if len(p) > c.n {
+		n := c.n
+		c.n = 0
+		return n, errors.New("broken pipe")
+	}
+	c.n -= len(p)
+	return len(p), nil
+}
+
+func (c *partialConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *partialConn) Close() error { return nil }
+
+// mockDial makes dials return conns received from returned channel
+func mockDial(t *testing.T) chan net.Conn {
+	conns := make(chan net.Conn)
+	dialConn = func(d *net.Dialer, network, address string) (net.Conn, error) {
+		return <-conns, nil
+	}
+	t.Cleanup(func() {
+		dialConn = func(d *net.Dialer, network, address string) (net.Conn, error) {
+			return d.Dial(network, address)
+		}
+	})
+	return conns
+}
+
+func TestWriteNotWaitingDial(t *testing.T) {
+	conns := mockDial(t)
+	w := NewNetWriter(WithAddress("tcp", "collector:514"))
+	done := make(chan struct{})
+	go func() {
+		w.Write([]byte("one\n"))
+		w.Write([]byte("two\n"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected writes not waiting for dial")
+	}
+	if got := w.Buffered(); got != 8 {
+		t.Fatalf("want 8 bytes buffered while dialing, got %d", got)
+	}
+	client, server := net.Pipe()
+	lines := make(chan string, 10)
+	go func() {
+		s := bufio.NewScanner(server)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+	conns <- client
+	expectLine(t, lines, "one")
+	expectLine(t, lines, "two")
+	w.Close()
+}
+
+func TestPartialWriteTail(t *testing.T) {
+	conns := mockDial(t)
+	w := NewNetWriter(WithAddress("tcp", "collector:514"), WithBackoff(time.Millisecond, time.Millisecond))
+	w.Write([]byte("hello\n"))
+	conns <- &partialConn{n: 3}
+
+	client, server := net.Pipe()
+	received := make(chan string, 1)
+	go func() {
+		b, _ := ioutil.ReadAll(server)
+		received <- string(b)
+	}()
+	conns <- client
+	deadline := time.Now().Add(2 * time.Second)
+	for w.Buffered() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	w.Close()
+	if got := <-received; got != "lo\n" {
+		t.Fatalf("want only unsent tail %q resent, got %q", "lo\n", got)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	w := NewNetWriter(WithAddress("", "collector:514"))
+	if info := writer.Describe(w); info.Type != "tcp" || info.Name != "collector:514" {
+		t.Fatalf("expected network and address, got %+v", info)
+	}
+}
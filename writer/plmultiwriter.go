@@ -6,7 +6,6 @@ import (
 	"sync"
 
 	"github.com/derkan/nlog"
-	"github.com/derkan/nlog/pool"
 )
 
 // ParallelMultiWriter is multi writer with log level filtering
@@ -69,16 +68,28 @@ func (t *ParallelMultiWriter) Close() (err error) {
 	return
 }
 
+// Sync calls Sync of all writers. Lines queued for workers are not waited
+// for, Close drains queues, syncs and closes writers.
+func (t *ParallelMultiWriter) Sync() (err error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for i, w := range t.writers {
+		if werr := w.Sync(); werr != nil {
+			err = fmt.Errorf("%v, worker: %d, err: %v", err, i, werr)
+		}
+	}
+	return
+}
+
 // WriteIfLevel calls write if current le₺vel is satisfied
 func (t *ParallelMultiWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 	for _, w := range t.writers {
-		if lvl > w.GetLevel() {
+		if !w.Enabled(lvl) {
 			continue
 		}
-		w.ch <- pool.GetBuffer().AppendBytes(p)
-
+		w.queue(lvl, p)
 	}
 	return len(p), err
 }
@@ -15,10 +15,17 @@ import (
 type ParallelWriter struct {
 	w   io.WriteCloser
 	l   nlog.Level
-	ch  chan nlog.Buffer
+	s   LevelSet
+	ch  chan job
 	end chan bool
 }
 
+// job is a line queued for worker with its level
+type job struct {
+	lvl nlog.Level
+	buf nlog.Buffer
+}
+
 // Write implements io.Writer.
 func (l *ParallelWriter) Write(p []byte) (n int, err error) {
 	return l.w.Write(p)
@@ -29,46 +36,69 @@ func (l *ParallelWriter) Close() (err error) {
 	return l.w.Close()
 }
 
+// Sync calls Sync of wrapped writer if it implements Syncer. Lines queued
+// for worker are not waited for, Stop drains them and syncs before closing.
+func (l *ParallelWriter) Sync() error {
+	return SyncWriter(l.w)
+}
+
 // GetLevel returns log level of current writer
 func (l *ParallelWriter) GetLevel() nlog.Level {
 	return l.l
 }
 
+// Enabled returns true if lvl is in levels of current writer
+func (l *ParallelWriter) Enabled(lvl nlog.Level) bool {
+	return l.s.Has(lvl)
+}
+
 // WriteIfLevel calls write if current le₺vel is satisfied
 func (l *ParallelWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
-	if lvl > l.l {
+	if !l.s.Has(lvl) {
 		if p == nil {
 			return 0, nil
 		}
 		return len(p), nil
 	}
+	return l.write(lvl, p)
+}
+
+// write writes p of level lvl to wrapped writer, with its level if it is a
+// leveled writer
+func (l *ParallelWriter) write(lvl nlog.Level, p []byte) (n int, err error) {
 	if lw, ok := l.w.(LeveledWriter); ok {
 		return lw.WriteIfLevel(lvl, p)
 	}
 	return l.Write(p)
 }
 
+// queue queues p of level lvl for worker
+func (l *ParallelWriter) queue(lvl nlog.Level, p []byte) {
+	l.ch <- job{lvl: lvl, buf: pool.GetBuffer().AppendBytes(p)}
+}
+
 // Start starts worker for writer
 func (l *ParallelWriter) Start(wg *sync.WaitGroup) {
 	wg.Add(1)
 	go func() {
 		for {
 			select {
-			case p := <-l.ch: // worker has received job
-				l.Write(p.Bytes())
-				pool.PutBuffer(p)
+			case j := <-l.ch: // worker has received job
+				l.write(j.lvl, j.buf.Bytes())
+				pool.PutBuffer(j.buf)
 			case <-l.end:
 				defer wg.Done()
 				// wait until all data in channel is written or timeout happens before exiting:
 				for len(l.ch) > 0 {
 					select {
-					case p := <-l.ch:
-						l.Write(p.Bytes())
-						pool.PutBuffer(p)
+					case j := <-l.ch:
+						l.write(j.lvl, j.buf.Bytes())
+						pool.PutBuffer(j.buf)
 					case <-time.After(2):
 						break
 					}
 				}
+				l.Sync()
 				l.Close()
 				return
 			}
@@ -76,7 +106,8 @@ func (l *ParallelWriter) Start(wg *sync.WaitGroup) {
 	}()
 }
 
-// Stop stops worker for writer
+// Stop stops worker for writer after queued lines are written and writer is
+// synced
 func (l *ParallelWriter) Stop() {
 	l.end <- true
 }
@@ -84,10 +115,17 @@ func (l *ParallelWriter) Stop() {
 // NewParellelWriter returns a new instance of threadsafe writer which will
 // write when level is satisfied
 func NewParellelWriter(w io.WriteCloser, l nlog.Level, chanSize int) *ParallelWriter {
+	return NewParallelWriterLevels(w, LevelRange(nlog.FATAL, l), chanSize)
+}
+
+// NewParallelWriterLevels returns a new instance of parallel writer which
+// will write only lines of levels in s
+func NewParallelWriterLevels(w io.WriteCloser, s LevelSet, chanSize int) *ParallelWriter {
 	return &ParallelWriter{
 		w:   w,
-		l:   l,
-		ch:  make(chan nlog.Buffer, chanSize),
+		l:   s.Max(),
+		s:   s,
+		ch:  make(chan job, chanSize),
 		end: make(chan bool),
 	}
 }
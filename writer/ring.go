@@ -0,0 +1,269 @@
+package writer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/pool"
+)
+
+// DefaultRingSize is number of lines kept by RingWriter when 0 is given
+const DefaultRingSize = 1000
+
+// RingWriter is a flight recorder keeping last lines of verbose levels in
+// memory instead of writing them. Lines of level or more severe levels are
+// written to wrapped writer directly. When a line of trigger level or more
+// severe level is written, kept lines are written before it, so errors are
+// logged with the debug lines leading to them.
+//
+// Kept lines can also be written with Flush or on signals with DumpOnSignal,
+// and read over HTTP with handlers of ringhttp package. Rings can be
+// registered by name with RegisterRing to reach rings of writers built from
+// config files.
+//
+// It is concurrent safe.
+type RingWriter struct {
+	w       io.WriteCloser
+	level   nlog.Level
+	trigger nlog.Level
+
+	mu    sync.Mutex
+	lines []nlog.Buffer
+	head  int // index of oldest line
+	n     int // number of kept lines
+	name  string
+	stops []func() // stop funcs of DumpOnSignal
+}
+
+// rings holds rings registered by name
+var rings = struct {
+	sync.RWMutex
+	m map[string]*RingWriter
+}{m: make(map[string]*RingWriter)}
+
+// RegisterRing makes r available by name with LookupRing, like rings of
+// writers built from config files with ring_name. It replaces any ring
+// registered with the same name. r is unregistered when it is closed.
+func RegisterRing(name string, r *RingWriter) {
+	rings.Lock()
+	defer rings.Unlock()
+	rings.m[name] = r
+	r.mu.Lock()
+	r.name = name
+	r.mu.Unlock()
+}
+
+// LookupRing returns ring registered with given name.
+func LookupRing(name string) (*RingWriter, bool) {
+	rings.RLock()
+	defer rings.RUnlock()
+	r, ok := rings.m[name]
+	return r, ok
+}
+
+// NewRingWriter returns a new RingWriter writing lines of level and more
+// severe levels to w, keeping last size lines of less severe levels in
+// memory until a line of trigger or more severe level is written.
+func NewRingWriter(w io.WriteCloser, level nlog.Level, size int, trigger nlog.Level) *RingWriter {
+	if size <= 0 {
+		size = DefaultRingSize
+	}
+	return &RingWriter{
+		w:       w,
+		level:   level,
+		trigger: trigger,
+		lines:   make([]nlog.Buffer, size),
+	}
+}
+
+// Write implements io.Writer, p is written to wrapped writer.
+func (r *RingWriter) Write(p []byte) (n int, err error) {
+	return r.w.Write(p)
+}
+
+// WriteIfLevel keeps p in memory if lvl is less severe than level, writes it
+// otherwise. Kept lines are written before p if lvl is trigger level or more
+// severe.
+func (r *RingWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lvl > r.level {
+		r.keep(p)
+		return len(p), nil
+	}
+	if lvl <= r.trigger {
+		if err = r.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if lw, ok := r.w.(LeveledWriter); ok {
+		return lw.WriteIfLevel(lvl, p)
+	}
+	return r.w.Write(p)
+}
+
+// GetLevel returns nlog.DEBUG, all levels are written or kept.
+func (r *RingWriter) GetLevel() nlog.Level {
+	return nlog.DEBUG
+}
+
+// Enabled returns true, all levels are written or kept.
+func (r *RingWriter) Enabled(lvl nlog.Level) bool {
+	return true
+}
+
+// Len returns number of kept lines.
+func (r *RingWriter) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// Dump writes kept lines to w, oldest first, keeping them. Lines are copied
+// before writing, so a slow w does not block writes to ring.
+func (r *RingWriter) Dump(w io.Writer) error {
+	r.mu.Lock()
+	lines := make([][]byte, 0, r.n)
+	r.each(func(b nlog.Buffer) error {
+		lines = append(lines, append([]byte(nil), b.Bytes()...))
+		return nil
+	})
+	r.mu.Unlock()
+	for _, line := range lines {
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes kept lines to wrapped writer, oldest first, and forgets them.
+func (r *RingWriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flush()
+}
+
+// Sync calls Sync of wrapped writer if it implements Syncer. Kept lines are
+// not written.
+func (r *RingWriter) Sync() error {
+	return SyncWriter(r.w)
+}
+
+// Close stops handling signals of DumpOnSignal, unregisters ring and closes
+// wrapped writer, kept lines are dropped.
+func (r *RingWriter) Close() error {
+	r.mu.Lock()
+	stops, name := r.stops, r.name
+	r.stops, r.name = nil, ""
+	r.mu.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+	if name != "" {
+		rings.Lock()
+		// ring may be replaced by a ring of reloaded config
+		if rings.m[name] == r {
+			delete(rings.m, name)
+		}
+		rings.Unlock()
+	}
+
+	r.mu.Lock()
+	r.reset()
+	r.mu.Unlock()
+	return r.w.Close()
+}
+
+// DumpOnSignal flushes kept lines to wrapped writer whenever one of sigs is
+// received, using SIGUSR2 if none is given. It is a no-op on platforms without
+// SIGUSR2 if sigs is empty. Returned function stops handling signals, which is
+// also done by Close.
+func (r *RingWriter) DumpOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = dumpSignals
+	}
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ch:
+				if err := r.Flush(); err != nil {
+					fmt.Fprintf(os.Stderr, "ring writer: can't dump lines, err: %v\n", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			<-exited
+		})
+	}
+	r.mu.Lock()
+	r.stops = append(r.stops, stop)
+	r.mu.Unlock()
+	return stop
+}
+
+// keep copies p into ring, overwriting oldest line if ring is full.
+func (r *RingWriter) keep(p []byte) {
+	i := (r.head + r.n) % len(r.lines)
+	if r.n == len(r.lines) {
+		r.head = (r.head + 1) % len(r.lines)
+	} else {
+		r.n++
+	}
+	if r.lines[i] == nil {
+		r.lines[i] = pool.GetBuffer()
+	}
+	r.lines[i].Reset()
+	r.lines[i].AppendBytes(p)
+}
+
+// flush writes kept lines to wrapped writer and forgets them.
+func (r *RingWriter) flush() error {
+	err := r.each(func(b nlog.Buffer) error {
+		_, err := r.w.Write(b.Bytes())
+		return err
+	})
+	// buffers are kept for reuse
+	r.head, r.n = 0, 0
+	return err
+}
+
+// each calls fn with kept lines, oldest first, stopping at first error.
+func (r *RingWriter) each(fn func(b nlog.Buffer) error) error {
+	for k := 0; k < r.n; k++ {
+		if err := fn(r.lines[(r.head+k)%len(r.lines)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset forgets kept lines, returning their buffers to pool.
+func (r *RingWriter) reset() {
+	for i, b := range r.lines {
+		if b != nil {
+			pool.PutBuffer(b)
+			r.lines[i] = nil
+		}
+	}
+	r.head, r.n = 0, 0
+}
@@ -0,0 +1,8 @@
+// +build windows plan9
+
+package writer
+
+import "os"
+
+// dumpSignals are signals handled by RingWriter.DumpOnSignal by default.
+var dumpSignals []os.Signal
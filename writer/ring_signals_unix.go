@@ -0,0 +1,11 @@
+// +build !windows,!plan9
+
+package writer
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals are signals handled by RingWriter.DumpOnSignal by default.
+var dumpSignals = []os.Signal{syscall.SIGUSR2}
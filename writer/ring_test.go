@@ -0,0 +1,79 @@
+package writer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+)
+
+func TestRingWriterTrigger(t *testing.T) {
+	rec := &recorder{}
+	r := NewRingWriter(rec, nlog.INFO, 2, nlog.ERROR)
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug 1\n"))
+	r.WriteIfLevel(nlog.INFO, []byte("info\n"))
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug 2\n"))
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug 3\n"))
+	if got, want := rec.get(), []string{"info\n"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if r.Len() != 2 {
+		t.Fatalf("expected 2 kept lines, got %d", r.Len())
+	}
+	r.WriteIfLevel(nlog.WARNING, []byte("warning\n"))
+	r.WriteIfLevel(nlog.ERROR, []byte("error\n"))
+	want := []string{"info\n", "warning\n", "debug 2\n", "debug 3\n", "error\n"}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if r.Len() != 0 {
+		t.Fatalf("expected ring emptied, got %d lines", r.Len())
+	}
+	r.WriteIfLevel(nlog.FATAL, []byte("fatal\n"))
+	if got := rec.get(); got[len(got)-1] != "fatal\n" || len(got) != 6 {
+		t.Fatalf("expected only fatal line written, got %q", got)
+	}
+}
+
+// blockingWriter blocks writes until release is closed
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return len(p), nil
+}
+
+func TestRingWriterDumpSlow(t *testing.T) {
+	r := NewRingWriter(&recorder{}, nlog.INFO, 10, nlog.ERROR)
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	bw := &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	done := make(chan error)
+	go func() { done <- r.Dump(bw) }()
+	<-bw.started
+
+	written := make(chan struct{})
+	go func() {
+		r.WriteIfLevel(nlog.DEBUG, []byte("debug 2\n"))
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected writes not blocked by slow dump")
+	}
+	close(bw.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 2 {
+		t.Fatalf("expected 2 kept lines, got %d", r.Len())
+	}
+}
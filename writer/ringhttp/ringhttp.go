@@ -0,0 +1,51 @@
+// Package ringhttp serves kept lines of ring writers over HTTP, keeping
+// net/http out of writer package.
+package ringhttp
+
+import (
+	"net/http"
+
+	"github.com/derkan/nlog/writer"
+)
+
+// Handler returns a handler writing kept lines of r as plain text for GET
+// requests. POST requests flush kept lines to wrapped writer of r.
+func Handler(r *writer.RingWriter) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		serve(r, rw, req)
+	})
+}
+
+// NamedHandler returns a handler like Handler for ring registered with
+// writer.RegisterRing, like rings of writers built from config files with
+// ring_name. Ring is looked up on each request, so rings of reloaded configs
+// are served. It responds with 404 if no ring is registered with name.
+func NamedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		r, ok := writer.LookupRing(name)
+		if !ok {
+			http.Error(rw, "ring "+name+" not found", http.StatusNotFound)
+			return
+		}
+		serve(r, rw, req)
+	})
+}
+
+func serve(r *writer.RingWriter, rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if req.Method == http.MethodGet {
+			r.Dump(rw)
+		}
+	case http.MethodPost:
+		if err := r.Flush(); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		rw.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
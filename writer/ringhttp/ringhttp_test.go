@@ -0,0 +1,88 @@
+package ringhttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/writer"
+)
+
+type recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *recorder) Close() error { return nil }
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.String()
+}
+
+func TestHandler(t *testing.T) {
+	rec := &recorder{}
+	r := writer.NewRingWriter(rec, nlog.INFO, 10, nlog.ERROR)
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug 1\n"))
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug 2\n"))
+	h := Handler(r)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/ring", nil))
+	if resp.Code != http.StatusOK || resp.Body.String() != "debug 1\ndebug 2\n" {
+		t.Fatalf("expected kept lines, got %d %q", resp.Code, resp.Body.String())
+	}
+	if rec.String() != "" || r.Len() != 2 {
+		t.Fatal("expected lines kept after GET")
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/debug/ring", nil))
+	if resp.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.Code)
+	}
+	if got := rec.String(); got != "debug 1\ndebug 2\n" || r.Len() != 0 {
+		t.Fatalf("expected lines flushed after POST, got %q", got)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/debug/ring", nil))
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, resp.Code)
+	}
+}
+
+func TestNamedHandler(t *testing.T) {
+	h := NamedHandler("app")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/ring", nil))
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, resp.Code)
+	}
+
+	r := writer.NewRingWriter(&recorder{}, nlog.INFO, 10, nlog.ERROR)
+	writer.RegisterRing("app", r)
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/ring", nil))
+	if resp.Code != http.StatusOK || resp.Body.String() != "debug\n" {
+		t.Fatalf("expected kept lines of registered ring, got %d %q", resp.Code, resp.Body.String())
+	}
+
+	r.Close()
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/ring", nil))
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("expected ring unregistered on Close, got %d", resp.Code)
+	}
+}
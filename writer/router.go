@@ -0,0 +1,186 @@
+package writer
+
+import (
+	"strings"
+
+	"github.com/derkan/nlog"
+)
+
+// Route sends lines matching all of its rules to its writer
+type Route struct {
+	// Writer receives matching lines of levels it is enabled for
+	Writer LeveledWriter
+	// Loggers routes only lines of sub loggers whose names start with one of
+	// these prefixes. Empty routes lines of all loggers.
+	Loggers []string
+	// Match routes only lines for which it returns true if set.
+	Match func(e Entry) bool
+	// MatchInfo describes Match for Describe, like service=db,api. It is set
+	// by MatchField.
+	MatchInfo string
+	// Final stops routing of matched lines to following routes.
+	Final bool
+}
+
+// NewRoute returns a route sending lines of levels w is enabled for to w,
+// which can be narrowed by setting other fields of route.
+func NewRoute(w LeveledWriter) Route {
+	return Route{Writer: w}
+}
+
+// MatchField sets Match of route to HasField(key, values...), describing it
+// in MatchInfo as key or key=values.
+func (r *Route) MatchField(key string, values ...string) {
+	r.Match = HasField(key, values...)
+	r.MatchInfo = key
+	if len(values) > 0 {
+		r.MatchInfo += "=" + strings.Join(values, ",")
+	}
+}
+
+// HasField returns a Route.Match function matching lines having field key.
+// If values are given, value of field must be one of them.
+func HasField(key string, values ...string) func(e Entry) bool {
+	return func(e Entry) bool {
+		for _, f := range e.Fields {
+			if f.Key != key {
+				continue
+			}
+			if len(values) == 0 {
+				return true
+			}
+			for _, v := range values {
+				if f.Value == v {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// RouterWriter sends each line to writers of routes it matches, in route order.
+// Lines are matched by level range, sub logger name and fields. Lines written
+// by json and console formatters are parsed only if a route needs logger name
+// or fields.
+//
+// It is concurrent safe if writers of routes are.
+type RouterWriter struct {
+	routes []Route
+	parse  bool
+}
+
+// NewRouterWriter returns a new RouterWriter with given routes.
+func NewRouterWriter(routes ...Route) *RouterWriter {
+	r := &RouterWriter{routes: routes}
+	for _, route := range routes {
+		if len(route.Loggers) > 0 || route.Match != nil {
+			r.parse = true
+		}
+	}
+	return r
+}
+
+// Write implements io.Writer. Level of p is read from the line, INFO is used
+// if it can't be found.
+func (r *RouterWriter) Write(p []byte) (n int, err error) {
+	lvl := nlog.INFO
+	if e, ok := ParseLine(p); ok {
+		if l, ok := LevelFromString(e.Level); ok {
+			lvl = l
+		}
+	}
+	return r.WriteIfLevel(lvl, p)
+}
+
+// WriteIfLevel writes p to writers of matching routes. First error of writers
+// is returned.
+func (r *RouterWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	var e Entry
+	parsed := false
+	for _, route := range r.routes {
+		if !route.Writer.Enabled(lvl) {
+			continue
+		}
+		if r.parse && !parsed && (len(route.Loggers) > 0 || route.Match != nil) {
+			e, _ = ParseLine(p)
+			parsed = true
+		}
+		if len(route.Loggers) > 0 && !hasPrefix(e.Logger, route.Loggers) {
+			continue
+		}
+		if route.Match != nil && !route.Match(e) {
+			continue
+		}
+		if _, errW := route.Writer.WriteIfLevel(lvl, p); err == nil {
+			err = errW
+		}
+		if route.Final {
+			break
+		}
+	}
+	return len(p), err
+}
+
+// Enabled returns true if a route writer is enabled for lvl
+func (r *RouterWriter) Enabled(lvl nlog.Level) bool {
+	for _, route := range r.routes {
+		if route.Writer.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLevel returns least severe level of route writers
+func (r *RouterWriter) GetLevel() nlog.Level {
+	lvl := nlog.FATAL
+	for _, route := range r.routes {
+		if l := route.Writer.GetLevel(); l > lvl {
+			lvl = l
+		}
+	}
+	return lvl
+}
+
+// Sync syncs all route writers. Writers shared by routes are synced once.
+func (r *RouterWriter) Sync() (err error) {
+	r.each(func(w LeveledWriter) {
+		if errS := w.Sync(); err == nil {
+			err = errS
+		}
+	})
+	return err
+}
+
+// Close closes all route writers. Writers shared by routes are closed once.
+func (r *RouterWriter) Close() (err error) {
+	r.each(func(w LeveledWriter) {
+		if errC := w.Close(); err == nil {
+			err = errC
+		}
+	})
+	return err
+}
+
+// each calls fn once for each distinct route writer.
+func (r *RouterWriter) each(fn func(w LeveledWriter)) {
+	seen := make(map[LeveledWriter]bool, len(r.routes))
+	for _, route := range r.routes {
+		if seen[route.Writer] {
+			continue
+		}
+		seen[route.Writer] = true
+		fn(route.Writer)
+	}
+}
+
+// hasPrefix reports whether name starts with one of prefixes.
+func hasPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
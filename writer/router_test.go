@@ -0,0 +1,130 @@
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/derkan/nlog"
+)
+
+func TestRouterWriterLevels(t *testing.T) {
+	errs, app := &recorder{}, &recorder{}
+	r := NewRouterWriter(
+		NewRoute(NewWriter(errs, nlog.ERROR)),
+		NewRoute(NewWriter(app, nlog.DEBUG)),
+	)
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	r.WriteIfLevel(nlog.ERROR, []byte("error\n"))
+	r.WriteIfLevel(nlog.FATAL, []byte("fatal\n"))
+	if got, want := errs.get(), []string{"error\n", "fatal\n"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q in error writer, got %q", want, got)
+	}
+	if got, want := app.get(), []string{"debug\n", "error\n", "fatal\n"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q in app writer, got %q", want, got)
+	}
+	if lvl := r.GetLevel(); lvl != nlog.DEBUG {
+		t.Fatalf("expected level DEBUG, got %v", lvl)
+	}
+}
+
+func TestRouterWriterLevelRange(t *testing.T) {
+	info := &recorder{}
+	r := NewRouterWriter(NewRoute(NewWriterLevels(info, LevelRange(nlog.WARNING, nlog.INFO))))
+	r.WriteIfLevel(nlog.ERROR, []byte("error\n"))
+	r.WriteIfLevel(nlog.WARNING, []byte("warning\n"))
+	r.WriteIfLevel(nlog.INFO, []byte("info\n"))
+	r.WriteIfLevel(nlog.DEBUG, []byte("debug\n"))
+	if got, want := info.get(), []string{"warning\n", "info\n"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRouterWriterLoggersAndFields(t *testing.T) {
+	db, audit, app := &recorder{}, &recorder{}, &recorder{}
+	dbRoute := NewRoute(NewWriter(db, nlog.DEBUG))
+	dbRoute.Loggers = []string{"db"}
+	auditRoute := NewRoute(NewWriter(audit, nlog.DEBUG))
+	auditRoute.Match = HasField("audit", "true")
+	auditRoute.Final = true
+	r := NewRouterWriter(dbRoute, auditRoute, NewRoute(NewWriter(app, nlog.DEBUG)))
+
+	lines := []string{
+		`{"time":"2026/10/18 10:00:00","level":"INF","logger":"db.pool","msg":"connected"}` + "\n",
+		`{"time":"2026/10/18 10:00:01","level":"INF","msg":"login","audit":true,"user":"joe"}` + "\n",
+		"2026/10/18 10:00:02 WRN \x1b[1;32m[db]\x1b[0m slow query took=3s\n",
+		"2026/10/18 10:00:03 INF logout  user=\"joe\" audit=true\n",
+		"2026/10/18 10:00:04 INF done\n",
+	}
+	for _, line := range lines {
+		r.Write([]byte(line))
+	}
+	if got, want := db.get(), []string{lines[0], lines[2]}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q in db writer, got %q", want, got)
+	}
+	if got, want := audit.get(), []string{lines[1], lines[3]}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q in audit writer, got %q", want, got)
+	}
+	if got, want := app.get(), []string{lines[0], lines[2], lines[4]}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q in app writer, got %q", want, got)
+	}
+}
+
+func TestRouterWriterCloseShared(t *testing.T) {
+	rec := &recorder{}
+	w := NewWriter(rec, nlog.DEBUG)
+	r := NewRouterWriter(NewRoute(w), NewRoute(w))
+	r.WriteIfLevel(nlog.INFO, []byte("line\n"))
+	if got := rec.get(); len(got) != 2 {
+		t.Fatalf("expected line written by both routes, got %q", got)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.closed {
+		t.Fatal("expected writer closed")
+	}
+}
+
+func TestParseConsoleLine(t *testing.T) {
+	for line, want := range map[string]Entry{
+		"2026/10/18 10:00:02 WRN \x1b[1;32m[db]\x1b[0m slow query took=3s main.go:12\n": {
+			Time: "2026/10/18 10:00:02", Level: "WRN", Logger: "db", Message: "slow query",
+			Fields: []Field{{"took", "3s"}},
+		},
+		"1760781602 \x1b[31mERR\x1b[0m failed  err=\"a \\\"b\\\"\" n=1\n": {
+			Time: "1760781602", Level: "ERR", Message: "failed",
+			Fields: []Field{{"err", `a "b"`}, {"n", "1"}},
+		},
+		"DBG started": {Level: "DBG", Message: "started"},
+	} {
+		e, ok := ParseConsoleLine([]byte(line))
+		if !ok {
+			t.Fatalf("can't parse %q", line)
+		}
+		if !reflect.DeepEqual(e, want) {
+			t.Fatalf("parsing %q: expected %+v, got %+v", line, want, e)
+		}
+	}
+	if _, ok := ParseConsoleLine([]byte("no level here\n")); ok {
+		t.Fatal("expected line without level not parsed")
+	}
+}
+
+func TestRouterWriterDescribe(t *testing.T) {
+	db := NewRoute(NewWriter(&recorder{}, nlog.ERROR))
+	db.Loggers = []string{"db"}
+	db.MatchField("service", "db", "cache")
+	db.Final = true
+	custom := NewRoute(NewWriter(&recorder{}, nlog.DEBUG))
+	custom.Match = func(e Entry) bool { return true }
+	info := NewRouterWriter(db, custom).Describe()
+	if len(info.Writers) != 2 {
+		t.Fatalf("expected info of 2 routes, got %+v", info)
+	}
+	if w := info.Writers[0]; !reflect.DeepEqual(w.Loggers, []string{"db"}) || w.Match != "service=db,cache" || !w.Final {
+		t.Fatalf("expected rules of route, got %+v", w)
+	}
+	if w := info.Writers[1]; w.Match != "func" || w.Final {
+		t.Fatalf("expected custom match described, got %+v", w)
+	}
+}
@@ -0,0 +1,27 @@
+package writer
+
+import (
+	"os"
+)
+
+// StdWriter wraps a process wide stream like os.Stdout or os.Stderr, so
+// closing writers of a logger, like on Reload or Flush, does not close it.
+// Sync is called on file.
+type StdWriter struct {
+	*os.File
+}
+
+// NewStdWriter returns f wrapped in a StdWriter
+func NewStdWriter(f *os.File) StdWriter {
+	return StdWriter{File: f}
+}
+
+// Close does not close wrapped file.
+func (s StdWriter) Close() error {
+	return nil
+}
+
+// Describe returns info of wrapped file
+func (s StdWriter) Describe() Info {
+	return Describe(s.File)
+}
@@ -0,0 +1,106 @@
+package syslog
+
+import (
+	"crypto/tls"
+
+	"github.com/derkan/nlog"
+	nw "github.com/derkan/nlog/writer/netwriter"
+)
+
+// Option is function type used for setting syslog writer config attributes
+type option func(*Writer)
+
+// WithAddress sets network and address of syslog server.
+// Network is one of udp, tcp, unix or unixgram.
+// Local syslog socket is used if not set.
+func WithAddress(network, address string) option {
+	return func(c *Writer) {
+		c.Network = network
+		c.Address = address
+	}
+}
+
+// WithFormat sets message format, RFC5424 or RFC3164. It defaults to RFC5424.
+func WithFormat(format Format) option {
+	return func(c *Writer) {
+		c.Format = format
+	}
+}
+
+// WithFraming sets how messages are delimited on stream connections.
+// It defaults to octet counting.
+func WithFraming(framing nw.Framing) option {
+	return func(c *Writer) {
+		c.Framing = framing
+	}
+}
+
+// WithTLS enables TLS for tcp connections with given config.
+func WithTLS(cfg *tls.Config) option {
+	return func(c *Writer) {
+		c.TLSConfig = cfg
+	}
+}
+
+// WithFacility sets syslog facility. It defaults to LOG_USER.
+func WithFacility(facility Facility) option {
+	return func(c *Writer) {
+		c.Facility = facility
+	}
+}
+
+// WithHostname sets HOSTNAME field. It defaults to os.Hostname().
+func WithHostname(hostname string) option {
+	return func(c *Writer) {
+		c.Hostname = hostname
+	}
+}
+
+// WithAppName sets APP-NAME field. It defaults to process name.
+func WithAppName(appName string) option {
+	return func(c *Writer) {
+		c.AppName = appName
+	}
+}
+
+// WithProcID sets PROCID field. It defaults to process id.
+func WithProcID(procID string) option {
+	return func(c *Writer) {
+		c.ProcID = procID
+	}
+}
+
+// WithMsgID sets MSGID field of RFC 5424 messages.
+func WithMsgID(msgID string) option {
+	return func(c *Writer) {
+		c.MsgID = msgID
+	}
+}
+
+// WithSDID sets SD-ID used for log fields. It defaults to DefaultSDID.
+func WithSDID(sdID string) option {
+	return func(c *Writer) {
+		c.SDID = sdID
+	}
+}
+
+// WithLevel sets most verbose level written. It defaults to nlog.DEBUG.
+func WithLevel(level nlog.Level) option {
+	return func(c *Writer) {
+		c.Level = level
+	}
+}
+
+// NewSyslogWriter returns a new instance of Writer
+func NewSyslogWriter(opts ...option) *Writer {
+	i := &Writer{
+		Facility: LOG_USER,
+		Framing:  nw.OctetCountingFraming,
+		Level:    nlog.DEBUG,
+	}
+	// Loop through each option and set
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
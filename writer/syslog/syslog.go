@@ -0,0 +1,398 @@
+// Package syslog provides a native syslog client writing RFC 5424 or
+// RFC 3164 messages to local or remote syslog servers over UDP, TCP, TLS or
+// Unix sockets.
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/pool"
+	"github.com/derkan/nlog/writer"
+	nw "github.com/derkan/nlog/writer/netwriter"
+)
+
+// Format is syslog message format
+type Format int
+
+const (
+	// RFC5424 is the IETF syslog format with structured data
+	RFC5424 Format = iota
+	// RFC3164 is the BSD syslog format
+	RFC3164
+)
+
+// Facility is syslog facility
+type Facility int
+
+// Syslog facilities
+const (
+	LOG_KERN Facility = iota
+	LOG_USER
+	LOG_MAIL
+	LOG_DAEMON
+	LOG_AUTH
+	LOG_SYSLOG
+	LOG_LPR
+	LOG_NEWS
+	LOG_UUCP
+	LOG_CRON
+	LOG_AUTHPRIV
+	LOG_FTP
+	_ // unused
+	_ // unused
+	_ // unused
+	_ // unused
+	LOG_LOCAL0
+	LOG_LOCAL1
+	LOG_LOCAL2
+	LOG_LOCAL3
+	LOG_LOCAL4
+	LOG_LOCAL5
+	LOG_LOCAL6
+	LOG_LOCAL7
+)
+
+// FacilityNames maps facility names used in configs to facilities
+var FacilityNames = map[string]Facility{
+	"kern":     LOG_KERN,
+	"user":     LOG_USER,
+	"mail":     LOG_MAIL,
+	"daemon":   LOG_DAEMON,
+	"auth":     LOG_AUTH,
+	"syslog":   LOG_SYSLOG,
+	"lpr":      LOG_LPR,
+	"news":     LOG_NEWS,
+	"uucp":     LOG_UUCP,
+	"cron":     LOG_CRON,
+	"authpriv": LOG_AUTHPRIV,
+	"ftp":      LOG_FTP,
+	"local0":   LOG_LOCAL0,
+	"local1":   LOG_LOCAL1,
+	"local2":   LOG_LOCAL2,
+	"local3":   LOG_LOCAL3,
+	"local4":   LOG_LOCAL4,
+	"local5":   LOG_LOCAL5,
+	"local6":   LOG_LOCAL6,
+	"local7":   LOG_LOCAL7,
+}
+
+// severities maps log levels to syslog severities
+var severities = map[nlog.Level]int{
+	nlog.FATAL:   2, // crit
+	nlog.ERROR:   3, // err
+	nlog.WARNING: 4, // warning
+	nlog.INFO:    6, // info
+	nlog.DEBUG:   7, // debug
+}
+
+const (
+	// DefaultSDID is structured data id used for log fields
+	DefaultSDID = "nlog@32473"
+
+	nilValue       = "-"
+	rfc5424Time    = "2006-01-02T15:04:05.000000Z07:00"
+	rfc3164Time    = time.Stamp
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxProcIDLen   = 128
+	maxMsgIDLen    = 32
+	maxSDNameLen   = 32
+)
+
+// localSockets are tried in order when no address is given
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var (
+	// currentTime exists so it can be mocked out by tests.
+	currentTime = time.Now
+)
+
+// ensure we always implement writer.LeveledWriter
+var _ writer.LeveledWriter = (*Writer)(nil)
+
+// Writer is a syslog client implementing writer.LeveledWriter.
+//
+// Each written line is sent as a single syslog message with a severity
+// matching its log level. When a line is written by json formatter, RFC 5424
+// messages carry the log message as MSG and the remaining log fields as
+// STRUCTURED-DATA parameters under SDID. Other lines are sent as MSG as is.
+//
+// If Address is empty, the local syslog socket is used.
+//
+// Writers are built with NewSyslogWriter. Level of a zero Writer is
+// nlog.FATAL, so it writes only FATAL lines unless Level is set.
+type Writer struct {
+	// Network is one of udp, tcp, unix or unixgram. It defaults to udp when
+	// Address is set.
+	Network string `json:"network" yaml:"network"`
+
+	// Address is remote syslog server address, host:port for ip networks or a
+	// socket path for unix networks.
+	Address string `json:"address" yaml:"address"`
+
+	// Format is message format. It defaults to RFC5424.
+	Format Format `json:"format" yaml:"format"`
+
+	// Framing sets how messages are delimited on stream connections.
+	// NewSyslogWriter sets it to octet counting as recommended by RFC 6587.
+	Framing nw.Framing `json:"framing" yaml:"framing"`
+
+	// TLSConfig enables TLS on tcp connections when set.
+	TLSConfig *tls.Config `json:"-" yaml:"-"`
+
+	// Facility is syslog facility. NewSyslogWriter sets it to LOG_USER.
+	Facility Facility `json:"facility" yaml:"facility"`
+
+	// Hostname is HOSTNAME field. It defaults to os.Hostname().
+	Hostname string `json:"hostname" yaml:"hostname"`
+
+	// AppName is APP-NAME (TAG in RFC 3164) field. It defaults to process name.
+	AppName string `json:"app_name" yaml:"app_name"`
+
+	// ProcID is PROCID field. It defaults to process id.
+	ProcID string `json:"procid" yaml:"procid"`
+
+	// MsgID is MSGID field of RFC 5424 messages.
+	MsgID string `json:"msgid" yaml:"msgid"`
+
+	// SDID is SD-ID used for log fields. It defaults to DefaultSDID.
+	SDID string `json:"sd_id" yaml:"sd_id"`
+
+	// Level is most verbose level written. NewSyslogWriter sets it to
+	// nlog.DEBUG, it is nlog.FATAL for a zero Writer.
+	Level nlog.Level `json:"level" yaml:"level"`
+
+	// MinLevel is most severe level written. The default is nlog.FATAL,
+	// writing all levels up to Level.
+	MinLevel nlog.Level `json:"min_level" yaml:"min_level"`
+
+	once sync.Once
+	w    io.WriteCloser
+}
+
+// Write implements io.Writer. Severity is taken from level field of lines
+// written by json formatter, INFO is used otherwise.
+func (l *Writer) Write(p []byte) (n int, err error) {
+	lvl := nlog.INFO
+	if e, ok := writer.ParseJSONLine(p); ok {
+		if v, ok := writer.LevelFromString(e.Level); ok {
+			lvl = v
+		}
+	}
+	return l.WriteIfLevel(lvl, p)
+}
+
+// WriteIfLevel writes p with severity of given level if level is satisfied.
+func (l *Writer) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
+	if !l.Enabled(lvl) {
+		if p == nil {
+			return 0, nil
+		}
+		return len(p), nil
+	}
+	l.once.Do(l.init)
+
+	buf := pool.GetBuffer()
+	defer pool.PutBuffer(buf)
+	l.format(buf, lvl, p)
+	if _, err = l.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// GetLevel returns log level of current writer
+func (l *Writer) GetLevel() nlog.Level {
+	return l.Level
+}
+
+// Enabled returns true if lvl is between MinLevel and Level.
+func (l *Writer) Enabled(lvl nlog.Level) bool {
+	return lvl >= l.MinLevel && lvl <= l.Level
+}
+
+// Sync sends messages buffered while server was unreachable.
+func (l *Writer) Sync() error {
+	l.once.Do(l.init)
+	return writer.SyncWriter(l.w)
+}
+
+// Describe returns info of writer with its server address, see writer.Describe
+func (l *Writer) Describe() writer.Info {
+	return writer.Info{Type: "syslog", Name: l.Address}
+}
+
+// Close implements io.Closer.
+func (l *Writer) Close() error {
+	l.once.Do(l.init)
+	return l.w.Close()
+}
+
+// init sets defaults and builds transport.
+func (l *Writer) init() {
+	if l.Hostname == "" {
+		l.Hostname, _ = os.Hostname()
+	}
+	if l.AppName == "" {
+		l.AppName = filepath.Base(os.Args[0])
+	}
+	if l.ProcID == "" {
+		l.ProcID = strconv.Itoa(os.Getpid())
+	}
+	if l.SDID == "" {
+		l.SDID = DefaultSDID
+	}
+	network, address := l.Network, l.Address
+	if address == "" {
+		network, address = localSocket()
+	} else if network == "" {
+		network = "udp"
+	}
+	l.w = &nw.Writer{
+		Network:   network,
+		Address:   address,
+		Framing:   l.Framing,
+		TLSConfig: l.TLSConfig,
+	}
+}
+
+// localSocket returns network and address of local syslog socket.
+func localSocket() (network, address string) {
+	for _, path := range localSockets {
+		if _, err := os.Stat(path); err == nil {
+			return "unixgram", path
+		}
+	}
+	return "udp", "127.0.0.1:514"
+}
+
+// format writes a syslog message for p into buf.
+func (l *Writer) format(buf nlog.Buffer, lvl nlog.Level, p []byte) {
+	sev, ok := severities[lvl]
+	if !ok {
+		sev = severities[nlog.WARNING]
+	}
+	buf.AppendByte('<').AppendInt(int(l.Facility)*8 + sev).AppendByte('>')
+
+	t := currentTime()
+	if l.Format == RFC3164 {
+		buf.AppendString(t.Format(rfc3164Time), false).AppendByte(' ')
+		buf.AppendString(header(l.Hostname, maxHostnameLen), false).AppendByte(' ')
+		buf.AppendString(header(l.AppName, maxAppNameLen), false)
+		if l.ProcID != "" {
+			buf.AppendByte('[').AppendString(header(l.ProcID, maxProcIDLen), false).AppendByte(']')
+		}
+		buf.AppendString(": ", false).AppendBytes(trimNewline(p))
+		return
+	}
+
+	buf.AppendString("1 ", false)
+	buf.AppendString(t.Format(rfc5424Time), false).AppendByte(' ')
+	buf.AppendString(header(l.Hostname, maxHostnameLen), false).AppendByte(' ')
+	buf.AppendString(header(l.AppName, maxAppNameLen), false).AppendByte(' ')
+	buf.AppendString(header(l.ProcID, maxProcIDLen), false).AppendByte(' ')
+	buf.AppendString(header(l.MsgID, maxMsgIDLen), false).AppendByte(' ')
+
+	e, ok := writer.ParseJSONLine(p)
+	if !ok {
+		buf.AppendString(nilValue, false).AppendByte(' ').AppendBytes(trimNewline(p))
+		return
+	}
+	if e.Logger == "" && len(e.Fields) == 0 {
+		buf.AppendString(nilValue, false)
+	} else {
+		buf.AppendByte('[').AppendString(header(l.SDID, maxSDNameLen), false)
+		if e.Logger != "" {
+			appendParam(buf, "logger", e.Logger)
+		}
+		for _, f := range e.Fields {
+			appendParam(buf, f.Key, f.Value)
+		}
+		buf.AppendByte(']')
+	}
+	buf.AppendByte(' ').AppendString(e.Message, false)
+}
+
+// appendParam appends an SD-PARAM to buf.
+func appendParam(buf nlog.Buffer, key, val string) {
+	buf.AppendByte(' ').AppendString(sdName(key), false).AppendString(`="`, false)
+	for i := 0; i < len(val); i++ {
+		switch val[i] {
+		case '"', '\\', ']':
+			buf.AppendByte('\\')
+		}
+		buf.AppendByte(val[i])
+	}
+	buf.AppendByte('"')
+}
+
+// header returns s as a header field, printable US-ASCII without spaces and
+// at most max bytes long. Empty strings are returned as NILVALUE.
+func header(s string, max int) string {
+	if s == "" {
+		return nilValue
+	}
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// sdName returns s as an SD-NAME.
+func sdName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "_"
+	}
+	if len(s) > maxSDNameLen {
+		s = s[:maxSDNameLen]
+	}
+	return s
+}
+
+// trimNewline removes trailing line feeds from p.
+func trimNewline(p []byte) []byte {
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// ParseFacility returns facility for given name like local0.
+func ParseFacility(name string) (Facility, error) {
+	if f, ok := FacilityNames[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+	return LOG_USER, fmt.Errorf("unknown syslog facility %q", name)
+}
+
+// ParseFormat returns format for given name, rfc5424 or rfc3164.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "rfc5424", "5424":
+		return RFC5424, nil
+	case "rfc3164", "3164", "bsd":
+		return RFC3164, nil
+	}
+	return RFC5424, fmt.Errorf("unknown syslog format %q", name)
+}
@@ -0,0 +1,205 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/derkan/nlog"
+	"github.com/derkan/nlog/writer"
+)
+
+var fakeCurrentTime = time.Date(2026, 10, 18, 21, 30, 5, 123456000, time.UTC)
+
+func fakeTime() time.Time {
+	return fakeCurrentTime
+}
+
+func listenUDP(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+func readDatagram(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func newTestWriter(pc net.PacketConn, opts ...option) *Writer {
+	opts = append([]option{
+		WithAddress("udp", pc.LocalAddr().String()),
+		WithHostname("host"),
+		WithAppName("app"),
+		WithProcID("42"),
+	}, opts...)
+	return NewSyslogWriter(opts...)
+}
+
+func TestRFC5424StructuredData(t *testing.T) {
+	currentTime = fakeTime
+	defer func() { currentTime = time.Now }()
+	pc := listenUDP(t)
+	defer pc.Close()
+
+	w := newTestWriter(pc, WithFacility(LOG_LOCAL0), WithMsgID("req"))
+	defer w.Close()
+	line := `{"time":"2026/10/18 21:30:05","level":"ERR","logger":"db","msg":"query failed","table":"users","quote":"a\"b]c","n":3}` + "\n"
+	if _, err := w.WriteIfLevel(nlog.ERROR, []byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	want := `<131>1 2026-10-18T21:30:05.123456Z host app 42 req [nlog@32473 logger="db" table="users" quote="a\"b\]c" n="3"] query failed`
+	if got := readDatagram(t, pc); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRFC5424PlainLine(t *testing.T) {
+	currentTime = fakeTime
+	defer func() { currentTime = time.Now }()
+	pc := listenUDP(t)
+	defer pc.Close()
+
+	w := newTestWriter(pc)
+	defer w.Close()
+	w.WriteIfLevel(nlog.INFO, []byte("INF plain message a=1\n"))
+	want := `<14>1 2026-10-18T21:30:05.123456Z host app 42 - - INF plain message a=1`
+	if got := readDatagram(t, pc); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRFC3164(t *testing.T) {
+	currentTime = fakeTime
+	defer func() { currentTime = time.Now }()
+	pc := listenUDP(t)
+	defer pc.Close()
+
+	w := newTestWriter(pc, WithFormat(RFC3164), WithFacility(LOG_DAEMON))
+	defer w.Close()
+	w.WriteIfLevel(nlog.WARNING, []byte("WRN disk almost full\n"))
+	want := `<28>Oct 18 21:30:05 host app[42]: WRN disk almost full`
+	if got := readDatagram(t, pc); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestSeverityFromJSONLevel(t *testing.T) {
+	currentTime = fakeTime
+	defer func() { currentTime = time.Now }()
+	pc := listenUDP(t)
+	defer pc.Close()
+
+	w := newTestWriter(pc)
+	defer w.Close()
+	w.Write([]byte(`{"level":"DBG","msg":"dbg"}`))
+	want := `<15>1 2026-10-18T21:30:05.123456Z host app 42 - - dbg`
+	if got := readDatagram(t, pc); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestJSONKeys(t *testing.T) {
+	currentTime = fakeTime
+	defer func() { currentTime = time.Now }()
+	keys := writer.GetJSONKeys()
+	writer.SetJSONKeys(writer.JSONKeys{Time: "ts", Level: "lvl", Logger: "name", Message: "message"})
+	defer writer.SetJSONKeys(keys)
+	pc := listenUDP(t)
+	defer pc.Close()
+
+	w := newTestWriter(pc)
+	defer w.Close()
+	w.Write([]byte(`{"ts":"2026/10/18 21:30:05","lvl":"WRN","name":"db","message":"slow query","msg":"kept"}`))
+	want := `<12>1 2026-10-18T21:30:05.123456Z host app 42 - [nlog@32473 logger="db" msg="kept"] slow query`
+	if got := readDatagram(t, pc); got != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	pc := listenUDP(t)
+	defer pc.Close()
+
+	w := newTestWriter(pc, WithLevel(nlog.WARNING))
+	defer w.Close()
+	w.WriteIfLevel(nlog.INFO, []byte("skipped\n"))
+	w.WriteIfLevel(nlog.ERROR, []byte("sent\n"))
+	if got := readDatagram(t, pc); got[len(got)-4:] != "sent" {
+		t.Fatalf("want only error message to be sent, got %q", got)
+	}
+}
+
+func TestTCPOctetCounting(t *testing.T) {
+	currentTime = fakeTime
+	defer func() { currentTime = time.Now }()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		n, err := readOctetCount(r)
+		if err != nil {
+			return
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err == nil {
+			got <- string(msg)
+		}
+	}()
+
+	w := NewSyslogWriter(
+		WithAddress("tcp", ln.Addr().String()),
+		WithHostname("host"),
+		WithAppName("app"),
+		WithProcID("42"),
+	)
+	defer w.Close()
+	w.WriteIfLevel(nlog.INFO, []byte("over tcp\n"))
+	want := `<14>1 2026-10-18T21:30:05.123456Z host app 42 - - over tcp`
+	select {
+	case msg := <-got:
+		if msg != want {
+			t.Fatalf("want\n%s\ngot\n%s", want, msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+// readOctetCount reads an octet count followed by a space from r.
+func readOctetCount(r *bufio.Reader) (int, error) {
+	s, err := r.ReadString(' ')
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s[:len(s)-1])
+}
+
+func TestParseFacility(t *testing.T) {
+	if f, err := ParseFacility("LOCAL7"); err != nil || f != LOG_LOCAL7 {
+		t.Fatalf("want local7, got %v, err: %v", f, err)
+	}
+	if _, err := ParseFacility("nope"); err == nil {
+		t.Fatal("want error for unknown facility")
+	}
+}
@@ -25,6 +25,7 @@ type SyslogWriter interface {
 type syslogWriter struct {
 	w SyslogWriter
 	l nlog.Level
+	s LevelSet
 }
 
 var logMap = map[nlog.Level]syslog.Priority{
@@ -38,26 +39,47 @@ var logMap = map[nlog.Level]syslog.Priority{
 // SyslogLevelWriter wraps a SyslogWriter and call the right syslog level
 // method matching the level.
 func SysLogWrapper(w SyslogWriter, l nlog.Level) LeveledWriter {
-	return syslogWriter{w, l}
+	return SysLogLevelsWrapper(w, LevelRange(nlog.FATAL, l))
+}
+
+// SysLogLevelsWrapper wraps a SyslogWriter like SysLogWrapper, writing only
+// lines of levels in s.
+func SysLogLevelsWrapper(w SyslogWriter, s LevelSet) LeveledWriter {
+	return syslogWriter{w, s.Max(), s}
 }
 
 func (sw syslogWriter) Write(p []byte) (n int, err error) {
 	return sw.w.Write(p)
 }
 
+// Describe returns info of local syslog writer with levels it writes
+func (l syslogWriter) Describe() Info {
+	return Info{Type: "syslog", Levels: l.s.String()}
+}
+
 // Write implements io.WriteCloser.
 func (l syslogWriter) Close() (err error) {
 	return
 }
 
+// Sync does nothing, messages are sent on write
+func (l syslogWriter) Sync() error {
+	return nil
+}
+
 // GetLevel returns log level of current writer
 func (l syslogWriter) GetLevel() nlog.Level {
 	return l.l
 }
 
+// Enabled returns true if lvl is in levels of current writer
+func (l syslogWriter) Enabled(lvl nlog.Level) bool {
+	return l.s.Has(lvl)
+}
+
 // WriteLevel implements LevelWriter interface.
 func (sw syslogWriter) WriteIfLevel(lvl nlog.Level, p []byte) (n int, err error) {
-	if lvl > sw.l {
+	if !sw.s.Has(lvl) {
 		if p == nil {
 			return 0, nil
 		}